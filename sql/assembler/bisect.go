@@ -0,0 +1,102 @@
+package assembler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+/**
+ * Question: how expensive is bisecting?
+ * Answer: a batch with a single bad row costs about 2 * log2(rows) extra statements, each under its own savepoint. If
+ *         every row in the batch is bad (e.g. the table is missing) it degrades to about 2 * rows statements, which is
+ *         why it is opt-in.
+ */
+
+// subgroup rebuilds the batch out of the items between `start` and `end` of `group`
+func (op BulkInsert) subgroup(group QueryGroup, start int, end int) (QueryGroup, error) {
+	fields, err := op.fields()
+	if err != nil {
+		return QueryGroup{}, err
+	}
+
+	indices := group.DataIndices
+	if indices != nil {
+		indices = indices[start:end]
+	}
+
+	subgroup, err := op.sqlGroup(fields, group.DataStart+start, group.items[start:end], indices)
+	if err != nil {
+		return QueryGroup{}, err
+	}
+	subgroup.Batch = group.Batch
+	subgroup.hooks = group.hooks
+
+	return withStatementOf(subgroup, op.sqlStatement), nil
+}
+
+// subgroup rebuilds the batch out of the items between `start` and `end` of `group`. Overridden for the same reasons
+//          as `Queries()`
+func (op BulkUpsert) subgroup(group QueryGroup, start int, end int) (QueryGroup, error) {
+	op.updated = op.ColumnsUpdate
+	fields, err := op.fields()
+	if err != nil {
+		return QueryGroup{}, err
+	}
+
+	indices := group.DataIndices
+	if indices != nil {
+		indices = indices[start:end]
+	}
+
+	subgroup, err := op.sqlGroup(fields, group.DataStart+start, group.items[start:end], indices)
+	if err != nil {
+		return QueryGroup{}, err
+	}
+	subgroup.Batch = group.Batch
+	subgroup.hooks = group.hooks
+
+	return withStatementOf(subgroup, op.sqlStatement), nil
+}
+
+// bisect splits the failed batch in half and retries each half under its own savepoint, recursively, until the rows
+//        that fail on their own are found. These go into the result's `RowErrors`, while the halves that went through
+//        go into `Succeeded`.
+func (executor *txExecutor) bisect(ctx context.Context, tx boil.ContextExecutor, group QueryGroup, err error) error {
+	result := &executor.result
+
+	count := group.DataEnd - group.DataStart
+	if count <= 1 {
+		result.RowErrors = append(result.RowErrors, RowError{
+			Batch:     group.Batch,
+			DataIndex: group.DataIndex(0),
+			Row:       group.items[0].Interface(),
+			Err:       err,
+			tagNames:  group.tagNames,
+		})
+		return nil
+	}
+
+	half := count / 2
+	for _, bounds := range [][2]int{{0, half}, {half, count}} {
+		subgroup, err := executor.subgroup(group, bounds[0], bounds[1])
+		if err != nil {
+			return err
+		}
+
+		outcome, err := execSavepoint(ctx, tx, subgroup, executor.opts.Bind)
+		switch {
+		case err == nil:
+			result.succeed(subgroup, outcome)
+		case errors.Is(err, errSavepoint):
+			return err
+		default:
+			if err := executor.bisect(ctx, tx, subgroup, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package assembler
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+const maxSuggestionDistance = 3
+
+/**
+ * Question: isn't this just `boil.Infer()` after all?
+ * Answer: it takes the same `boil.Columns` values, but works them out of the `boil` tags rather than the generated
+ *         column lists we cannot get to (see the top of `insert.go`). Without those lists we cannot tell which columns
+ *         have defaults, so inferring simply takes every tagged column:
+ *             > insert: `Infer()` (or `None()`) is every tagged column, `Whitelist()` is exactly the columns given,
+ *               `Blacklist()` is every tagged column but the ones given, `Greylist()` is inferred plus the ones given
+ *             > update: the same, except that inferring starts from the insert columns minus the conflict targets, and
+ *               `None()` turns the upsert into `ON CONFLICT DO NOTHING`
+ *             -- update: the tag options leave some columns out when inferring, see the top of `fields.go`
+ *             -- update: and so can other tags than `boil`, see `TagNames()`
+ *             -- update: `DO NOTHING` only returns the rows it inserted, so its rows cannot be bound, see `checkBind()`
+ *
+ * Either way, every column has to have a `boil` tag by the time the constructors are done. Left alone, the unknown
+ * columns would go missing from the values but not from the SQL, and Postgres would complain about the count instead.
+ */
+
+// NewBulkInsertWith creates a new instance like `NewBulkInsert()`, with the columns selected through `boil.Columns`
+func NewBulkInsertWith(
+	data interface{},
+	table string,
+	columns boil.Columns,
+	opts ...Option,
+) (BulkInsert, error) {
+	dataType, dataValue, err := getSupportedData(data)
+	if err != nil {
+		return BulkInsert{}, err
+	}
+
+	op := BulkInsert{
+		Data:      data,
+		DataType:  dataType,
+		DataValue: dataValue,
+		Table:     table,
+	}
+	for _, opt := range opts {
+		opt(&op)
+	}
+
+	itemType := op.itemType()
+	op.Columns = getInsertColumns(itemType, columns, op.TagNames)
+	if isMapType(itemType) {
+		op.Columns = getInsertColumnsFrom(getMapColumns(dataType, dataValue), columns)
+		if len(op.Columns) == 0 {
+			return BulkInsert{}, pkgerrors.WithStack(ErrMapColumns)
+		}
+	}
+	if err := validateColumns(itemType, op.Columns, op.TagNames); err != nil {
+		return BulkInsert{}, err
+	}
+
+	return op, nil
+}
+
+// NewBulkUpsertWith creates a new instance like `NewBulkUpsert()`, with the columns selected through `boil.Columns`
+func NewBulkUpsertWith(
+	data interface{},
+	table string,
+	conflicts []string,
+	columnsInsert boil.Columns,
+	columnsUpdate boil.Columns,
+	opts ...Option,
+) (BulkUpsert, error) {
+	op, err := NewBulkInsertWith(data, table, columnsInsert, opts...)
+	if err != nil {
+		return BulkUpsert{}, err
+	}
+
+	columnsUpdateResolved := getUpdateColumns(op.itemType(), op.Columns, conflicts, columnsUpdate, op.TagNames)
+	if err := validateColumns(op.itemType(), columnsUpdateResolved, op.TagNames); err != nil {
+		return BulkUpsert{}, err
+	}
+
+	return BulkUpsert{
+		BulkInsert:      op,
+		ColumnsUpdate:   columnsUpdateResolved,
+		ConflictTargets: conflicts,
+	}, nil
+}
+
+// getSelection turns a plain list of columns into a selection, where nil means inferring them
+func getSelection(columns []string) boil.Columns {
+	if columns == nil {
+		return boil.Infer()
+	}
+
+	return boil.Whitelist(columns...)
+}
+
+// getInsertColumns works out the columns to insert from the tags of the item type, leaving out the ones tagged
+//                  `readonly`
+func getInsertColumns(itemType reflect.Type, columns boil.Columns, tagNames []string) []string {
+	inferred := getStructMapping(itemType, tagNames).filter(func(field structField) bool {
+		return !field.ReadOnly
+	})
+
+	return getInsertColumnsFrom(inferred, columns)
+}
+
+// getInsertColumnsFrom works out the columns to insert, starting from the ones inferred
+func getInsertColumnsFrom(inferred []string, columns boil.Columns) []string {
+	switch {
+	case columns.IsWhitelist():
+		return columns.Cols
+	case columns.IsBlacklist():
+		return withoutColumns(inferred, columns.Cols)
+	case columns.IsGreylist():
+		return withColumns(inferred, columns.Cols)
+	default:
+		return inferred
+	}
+}
+
+// getUpdateColumns works out the columns to update on conflict from the columns being inserted, leaving out the ones
+//                  tagged `pk`, and the ones tagged `omitempty` which may well hold `DEFAULT`
+func getUpdateColumns(
+	itemType reflect.Type,
+	insertColumns []string,
+	conflicts []string,
+	columns boil.Columns,
+	tagNames []string,
+) []string {
+	inferred := withoutColumns(insertColumns, conflicts)
+	inferred = withoutColumns(inferred, getPrimaryKeyColumns(itemType, tagNames))
+	inferred = withoutColumns(inferred, getOmitEmptyColumns(itemType, tagNames))
+
+	switch {
+	case columns.IsNone():
+		return []string{}
+	case columns.IsWhitelist():
+		return columns.Cols
+	case columns.IsBlacklist():
+		return withoutColumns(inferred, columns.Cols)
+	case columns.IsGreylist():
+		return withColumns(inferred, columns.Cols)
+	default:
+		return inferred
+	}
+}
+
+// getPrimaryKeyColumns lists the columns tagged `pk`
+func getPrimaryKeyColumns(itemType reflect.Type, tagNames []string) []string {
+	return getStructMapping(itemType, tagNames).filter(func(field structField) bool {
+		return field.PrimaryKey
+	})
+}
+
+// getReadOnlyColumns lists the columns tagged `readonly`
+func getReadOnlyColumns(itemType reflect.Type, tagNames []string) []string {
+	return getStructMapping(itemType, tagNames).filter(func(field structField) bool {
+		return field.ReadOnly
+	})
+}
+
+// getOmitEmptyColumns lists the columns tagged `omitempty`
+func getOmitEmptyColumns(itemType reflect.Type, tagNames []string) []string {
+	return getStructMapping(itemType, tagNames).filter(func(field structField) bool {
+		return field.OmitEmpty
+	})
+}
+
+// withColumns appends the extra columns that are not in the list yet
+func withColumns(columns []string, extra []string) []string {
+	output := slices.Clone(columns)
+	for _, column := range extra {
+		if !slices.Contains(output, column) {
+			output = append(output, column)
+		}
+	}
+
+	return output
+}
+
+// withoutColumns removes the excluded columns from the list
+func withoutColumns(columns []string, excluded []string) []string {
+	output := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if !slices.Contains(excluded, column) {
+			output = append(output, column)
+		}
+	}
+
+	return output
+}
+
+// validateColumns makes sure that every column to be written has a tag in the struct which is not `readonly`, and
+//                 that none of them is repeated. Columns claimed by more than one embedded struct at the same level
+//                 are reported as conflicts.
+func validateColumns(itemType reflect.Type, columns []string, tagNames []string) error {
+	mapping := getStructMapping(itemType, tagNames)
+	known := getStructColumns(itemType, tagNames)
+	conflicts := mapping.Conflicts
+	dynamic := isMapType(itemType)
+	columnErr := ColumnError{
+		Type:        itemType.String(),
+		Suggestions: make(map[string]string),
+		Conflicts:   make(map[string][]string),
+	}
+
+	seen := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		if seen[column] && !slices.Contains(columnErr.Duplicates, column) {
+			columnErr.Duplicates = append(columnErr.Duplicates, column)
+		}
+		seen[column] = true
+
+		// map rows take any column, only Postgres can tell
+		if dynamic {
+			continue
+		}
+
+		if field, found := mapping.field(column); found {
+			if field.ReadOnly && !slices.Contains(columnErr.ReadOnly, column) {
+				columnErr.ReadOnly = append(columnErr.ReadOnly, column)
+			}
+			continue
+		}
+		if slices.Contains(columnErr.Unknown, column) {
+			continue
+		}
+		if fields, found := conflicts[column]; found {
+			columnErr.Conflicts[column] = slices.Clone(fields)
+			continue
+		}
+
+		columnErr.Unknown = append(columnErr.Unknown, column)
+		if suggestion, ok := getClosestColumn(column, known); ok {
+			columnErr.Suggestions[column] = suggestion
+		}
+	}
+
+	if len(columnErr.Unknown) == 0 &&
+		len(columnErr.Duplicates) == 0 &&
+		len(columnErr.Conflicts) == 0 &&
+		len(columnErr.ReadOnly) == 0 {
+		return nil
+	}
+
+	return pkgerrors.WithStack(columnErr)
+}
+
+// getClosestColumn finds the known column that is the fewest edits away from `column`, ignoring case. Columns that
+//                  need more than a third of `column` rewritten are too far off to be worth suggesting.
+func getClosestColumn(column string, known []string) (string, bool) {
+	closest := ""
+	closestDistance := min(len(column)/3+1, maxSuggestionDistance) + 1
+	for _, candidate := range known {
+		distance := getEditDistance(strings.ToLower(column), strings.ToLower(candidate))
+		if distance < closestDistance {
+			closest = candidate
+			closestDistance = distance
+		}
+	}
+
+	return closest, closest != ""
+}
+
+// getEditDistance counts the insertions, deletions and substitutions needed to turn one string into the other
+func getEditDistance(left string, right string) int {
+	previous := make([]int, len(right)+1)
+	current := make([]int, len(right)+1)
+	for idx := range previous {
+		previous[idx] = idx
+	}
+
+	for leftIdx := 1; leftIdx <= len(left); leftIdx++ {
+		current[0] = leftIdx
+		for rightIdx := 1; rightIdx <= len(right); rightIdx++ {
+			cost := 1
+			if left[leftIdx-1] == right[rightIdx-1] {
+				cost = 0
+			}
+
+			current[rightIdx] = min(
+				previous[rightIdx]+1,
+				current[rightIdx-1]+1,
+				previous[rightIdx-1]+cost,
+			)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(right)]
+}
@@ -0,0 +1,304 @@
+package assembler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+func TestNewBulkInsertWith(t *testing.T) {
+	type SampleTable struct {
+		ID       int64  `boil:"id"`
+		Col01    string `boil:"col_01"`
+		Col02    string `boil:"col_02"`
+		Ignored  string `boil:"-"`
+		Untagged string
+	}
+
+	tcs := map[string]struct {
+		gvnColumns boil.Columns
+		expColumns []string
+	}{
+		"success__infer": {
+			gvnColumns: boil.Infer(),
+			expColumns: []string{"id", "col_01", "col_02"},
+		},
+		"success__none": {
+			gvnColumns: boil.None(),
+			expColumns: []string{"id", "col_01", "col_02"},
+		},
+		"success__whitelist": {
+			gvnColumns: boil.Whitelist("col_02", "id"),
+			expColumns: []string{"col_02", "id"},
+		},
+		"success__blacklist": {
+			gvnColumns: boil.Blacklist("id"),
+			expColumns: []string{"col_01", "col_02"},
+		},
+		"success__greylist": {
+			gvnColumns: boil.Greylist("col_01"),
+			expColumns: []string{"id", "col_01", "col_02"},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []*SampleTable{{ID: 1}}
+
+			// When
+			op, err := NewBulkInsertWith(data, "sample_table", tc.gvnColumns)
+
+			// Then
+			require.NoError(t, err)
+			require.Equal(t, tc.expColumns, op.Columns)
+		})
+	}
+}
+
+func TestNewBulkUpsertWith(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+		Col02 string `boil:"col_02"`
+	}
+
+	tcs := map[string]struct {
+		gvnInsert  boil.Columns
+		gvnUpdate  boil.Columns
+		expInsert  []string
+		expUpdate  []string
+		expSQLPart string
+	}{
+		"success__infer": {
+			gvnInsert:  boil.Infer(),
+			gvnUpdate:  boil.Infer(),
+			expInsert:  []string{"id", "col_01", "col_02"},
+			expUpdate:  []string{"col_01", "col_02"},
+			expSQLPart: "DO UPDATE SET\n",
+		},
+		"success__blacklist_update": {
+			gvnInsert:  boil.Infer(),
+			gvnUpdate:  boil.Blacklist("col_02"),
+			expInsert:  []string{"id", "col_01", "col_02"},
+			expUpdate:  []string{"col_01"},
+			expSQLPart: "DO UPDATE SET\n",
+		},
+		"success__greylist_update": {
+			gvnInsert:  boil.Blacklist("col_02"),
+			gvnUpdate:  boil.Greylist("col_02"),
+			expInsert:  []string{"id", "col_01"},
+			expUpdate:  []string{"col_01", "col_02"},
+			expSQLPart: "DO UPDATE SET\n",
+		},
+		"success__none_update": {
+			gvnInsert:  boil.Infer(),
+			gvnUpdate:  boil.None(),
+			expInsert:  []string{"id", "col_01", "col_02"},
+			expUpdate:  []string{},
+			expSQLPart: "DO NOTHING\n",
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []*SampleTable{{ID: 1}}
+
+			// When
+			op, err := NewBulkUpsertWith(data, "sample_table", []string{"id"}, tc.gvnInsert, tc.gvnUpdate)
+
+			// Then
+			require.NoError(t, err)
+			require.Equal(t, tc.expInsert, op.Columns)
+			require.Equal(t, tc.expUpdate, op.ColumnsUpdate)
+
+			groups, err := op.Queries()
+			require.NoError(t, err)
+			require.True(t, strings.Contains(groups[0].Statement.SQL, tc.expSQLPart))
+		})
+	}
+}
+
+func TestNewBulkInsert_InferColumns(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	// When
+	op, err := NewBulkInsert([]SampleTable{{ID: 1}}, "sample_table", nil)
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "col_01"}, op.Columns)
+}
+
+func TestValidateColumns(t *testing.T) {
+	type SampleTable struct {
+		ID        int64  `boil:"id"`
+		AssetID   string `boil:"asset_id"`
+		CreatedAt string `boil:"created_at"`
+	}
+
+	tcs := map[string]struct {
+		gvnColumns     []string
+		expUnknown     []string
+		expSuggestions map[string]string
+		expDuplicates  []string
+	}{
+		"success__known_columns": {
+			gvnColumns: []string{"id", "asset_id", "created_at"},
+		},
+		"failure__unknown_columns": {
+			gvnColumns:     []string{"id", "assetid", "Created_At", "voltage"},
+			expUnknown:     []string{"assetid", "Created_At", "voltage"},
+			expSuggestions: map[string]string{"assetid": "asset_id", "Created_At": "created_at"},
+		},
+		"failure__duplicate_columns": {
+			gvnColumns:     []string{"id", "asset_id", "id", "id"},
+			expSuggestions: map[string]string{},
+			expDuplicates:  []string{"id"},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// When
+			err := validateColumns(reflect.TypeFor[SampleTable](), tc.gvnColumns, nil)
+
+			// Then
+			if tc.expUnknown == nil && tc.expDuplicates == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var columnErr ColumnError
+			require.ErrorIs(t, err, ErrColumnInvalid)
+			require.ErrorAs(t, err, &columnErr)
+			require.Equal(t, tc.expUnknown, columnErr.Unknown)
+			require.Equal(t, tc.expSuggestions, columnErr.Suggestions)
+			require.Equal(t, tc.expDuplicates, columnErr.Duplicates)
+		})
+	}
+}
+
+func TestNewBulkUpsert_UnknownColumns(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	tcs := map[string]struct {
+		gvnInsert []string
+		gvnUpdate []string
+		expErr    string
+	}{
+		"failure__insert_columns": {
+			gvnInsert: []string{"id", "col_1"},
+			gvnUpdate: nil,
+			expErr:    `unknown columns "col_1" (did you mean "col_01"?)`,
+		},
+		"failure__update_columns": {
+			gvnInsert: []string{"id", "col_01"},
+			gvnUpdate: []string{"col_01", "col_01"},
+			expErr:    `duplicate columns ["col_01"]`,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// When
+			_, err := NewBulkUpsert([]SampleTable{{ID: 1}}, "sample_table", []string{"id"}, tc.gvnInsert, tc.gvnUpdate)
+
+			// Then
+			require.ErrorIs(t, err, ErrColumnInvalid)
+			require.Contains(t, err.Error(), tc.expErr)
+		})
+	}
+}
+
+func TestNewBulkUpsertWith_TagOptions(t *testing.T) {
+	type SampleTable struct {
+		ID        int64  `boil:"id,pk,readonly"`
+		Code      string `boil:"code,pk"`
+		Col01     string `boil:"col_01,omitempty"`
+		Col02     string `boil:"col_02"`
+		Generated string `boil:"generated,readonly"`
+	}
+
+	tcs := map[string]struct {
+		gvnInsert       boil.Columns
+		gvnUpdate       boil.Columns
+		expInsert       []string
+		expUpdate       []string
+		gvnReturningAll bool
+		expReturning    string
+		expErrReadOnly  []string
+	}{
+		"success__infer": {
+			gvnInsert:    boil.Infer(),
+			gvnUpdate:    boil.Infer(),
+			expInsert:    []string{"code", "col_01", "col_02"},
+			expUpdate:    []string{"col_02"},
+			expReturning: `RETURNING "code","col_01","col_02","id","generated"`,
+		},
+		"success__infer_returning_all": {
+			gvnInsert:       boil.Infer(),
+			gvnUpdate:       boil.Infer(),
+			gvnReturningAll: true,
+			expInsert:       []string{"code", "col_01", "col_02"},
+			expUpdate:       []string{"col_02"},
+			expReturning:    `RETURNING "id","code","col_01","col_02","generated"`,
+		},
+		"success__whitelist_omitempty": {
+			gvnInsert:    boil.Whitelist("code", "col_01"),
+			gvnUpdate:    boil.Infer(),
+			expInsert:    []string{"code", "col_01"},
+			expUpdate:    []string{},
+			expReturning: `RETURNING "code","col_01","id","generated"`,
+		},
+		"failure__insert_readonly": {
+			gvnInsert:      boil.Greylist("generated"),
+			gvnUpdate:      boil.Infer(),
+			expErrReadOnly: []string{"generated"},
+		},
+		"failure__update_readonly": {
+			gvnInsert:      boil.Infer(),
+			gvnUpdate:      boil.Whitelist("col_02", "id"),
+			expErrReadOnly: []string{"id"},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []SampleTable{{Code: "one"}}
+
+			// When
+			op, err := NewBulkUpsertWith(data, "sample_table", []string{"code"}, tc.gvnInsert, tc.gvnUpdate)
+
+			// Then
+			if tc.expErrReadOnly != nil {
+				var columnErr ColumnError
+				require.True(t, errors.As(err, &columnErr))
+				require.Equal(t, tc.expErrReadOnly, columnErr.ReadOnly)
+				require.Contains(t, err.Error(), "read-only columns")
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expInsert, op.Columns)
+			require.Equal(t, tc.expUpdate, op.ColumnsUpdate)
+
+			op.ReturningAll = tc.gvnReturningAll
+			groups, err := op.Queries()
+			require.NoError(t, err)
+			require.Contains(t, groups[0].Statement.SQL, tc.expReturning)
+		})
+	}
+}
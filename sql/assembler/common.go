@@ -1,9 +1,14 @@
 package assembler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"iter"
 	"math"
 	"reflect"
+	"slices"
+	"sort"
 	"time"
 
 	pkgerrors "github.com/pkg/errors"
@@ -15,11 +20,66 @@ const psqlMaxParamCount = math.MaxUint16
 
 // QueryGroup represents arguments needed for the batch query
 type QueryGroup struct {
-	Rows      []string
-	Args      []interface{}
-	DataStart int
-	DataEnd   int
-	Query     *queries.Query
+	Batch       int
+	Rows        []string
+	Args        []interface{}
+	DataStart   int
+	DataEnd     int
+	Query       *queries.Query
+	Statement   Statement
+	Fingerprint string
+	// DataIndices are where the rows of the batch sit in the data, only set when the rows are sorted. See `DataIndex()`
+	DataIndices []int
+
+	// items are the data held by this batch, kept around to be able to write back to them
+	items []reflect.Value
+	// defaults counts the `DEFAULT` keywords sent in place of arguments
+	defaults int
+	// tagNames are the struct tags the columns were read from, see `TagNames()`
+	tagNames []string
+	// hooks run around the statement, see `hooks.go`
+	hooks *groupHooks
+}
+
+// DataIndex returns where the `idx`-th row of the batch sits in the data
+func (group QueryGroup) DataIndex(idx int) int {
+	if group.DataIndices != nil {
+		return group.DataIndices[idx]
+	}
+
+	return group.DataStart + idx
+}
+
+// itemType returns the concrete type of the items of the batch, rather than `interface{}` for streams of them
+func (group QueryGroup) itemType() reflect.Type {
+	return getItem(group.items[0]).Type()
+}
+
+// getDataIndices lists where the rows between `start` and `end` sit in the data, which is `indices` when they were
+//                sorted
+func getDataIndices(start int, end int, indices []int) []int {
+	if indices != nil {
+		return indices
+	}
+
+	output := make([]int, 0, end-start)
+	for idx := start; idx < end; idx++ {
+		output = append(output, idx)
+	}
+
+	return output
+}
+
+// BatchSizesPowersOfTwo returns the batch sizes 1, 2, 4, ... up to the largest power of two that can fit in a single
+//                       statement. Sizes that do not fit the parameter limit for a given struct are dropped when the
+//                       batches are prepared.
+func BatchSizesPowersOfTwo() []int {
+	sizes := make([]int, 0, 16)
+	for size := 1; size <= psqlMaxParamCount; size *= 2 {
+		sizes = append(sizes, size)
+	}
+
+	return sizes
 }
 
 // GetCurrentTime returns the current time but in the context of how SQLBoiler is configured to make it consistent in
@@ -28,6 +88,47 @@ func GetCurrentTime() time.Time {
 	return time.Now().In(boil.GetLocation())
 }
 
+// collectGroups drains the batch iterator into a slice, stopping at the first error
+func collectGroups(batches iter.Seq2[QueryGroup, error]) ([]QueryGroup, error) {
+	groups := make([]QueryGroup, 0)
+	for group, err := range batches {
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// withStatement completes every batch coming out of `sqlData()` with its SQL statement and fingerprint
+func withStatement(
+	batches iter.Seq2[QueryGroup, error],
+	statement func(QueryGroup) string,
+) iter.Seq2[QueryGroup, error] {
+	return func(yield func(QueryGroup, error) bool) {
+		for group, err := range batches {
+			if err == nil {
+				group = withStatementOf(group, statement)
+			}
+
+			if !yield(group, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// withStatementOf completes a single batch with its SQL statement and fingerprint
+func withStatementOf(group QueryGroup, statement func(QueryGroup) string) QueryGroup {
+	sql := statement(group)
+	group.Query = queries.Raw(sql, group.Args...)
+	group.Statement = Statement{SQL: sql, Args: group.Args, tagNames: group.tagNames}
+	group.Fingerprint = getFingerprint(sql)
+
+	return group
+}
+
 // getBatchingInfo determine how many batches for bulk statements will be prepared based on the number of data involved
 //                 and the prevailing limit of parameters per statement. See `psqMaxParamCount`. This limit is
 //                 parameterized just for testing purposes.
@@ -44,37 +145,97 @@ func getBatchingInfo(dataCount int, fieldsCount int, limit int) (int, int) {
 	return batchLen, batchCount
 }
 
-// getStructFields gets field names of the struct based on the database column name -- this should base from the
-//                     `boil` metatdata of the struct
-func getStructFields(objValue reflect.Value, columns []string) ([]string, error) {
-	if objValue.Kind() == reflect.Ptr {
-		objValue = objValue.Elem()
+// getBatchSizes splits `dataCount` rows into the lengths of each batch. Without `sizes`, all batches are `batchLen`
+//               long except for the last one. With `sizes`, only the given lengths that fit in `batchLen` are used,
+//               largest first, so that the same handful of statements get prepared over and over. A length of 1 is
+//               always allowed on top of them so that the tail can be split into allowed lengths as well. Lists with
+//               non-positive lengths, or none that fit, are rejected rather than ignored.
+func getBatchSizes(dataCount int, batchLen int, sizes []int) ([]int, error) {
+	allowed := make([]int, 0, len(sizes)+1)
+	for _, size := range sizes {
+		if size <= 0 {
+			return nil, pkgerrors.Wrapf(ErrBatchSizes, "%v has non-positive sizes", sizes)
+		}
+		if size <= batchLen && !slices.Contains(allowed, size) {
+			allowed = append(allowed, size)
+		}
 	}
 
-	if objValue.Kind() != reflect.Struct {
-		return nil, pkgerrors.WithStack(ErrDataNotStruct)
+	switch {
+	case len(sizes) == 0:
+		allowed = append(allowed, batchLen)
+	case len(allowed) == 0:
+		return nil, pkgerrors.Wrapf(ErrBatchSizes, "none of %v fit in the %d rows a statement can hold", sizes, batchLen)
+	case !slices.Contains(allowed, 1):
+		allowed = append(allowed, 1)
 	}
+	sort.Sort(sort.Reverse(sort.IntSlice(allowed)))
 
-	// create the mapping
-	objType := objValue.Type()
-	fieldsCount := objValue.NumField()
-	mapping := make(map[string]string, fieldsCount)
+	output := make([]int, 0)
+	for remaining := dataCount; remaining > 0; {
+		// without `sizes`, the tail goes out as it is
+		length := remaining
+		for _, size := range allowed {
+			if size <= remaining {
+				length = size
+				break
+			}
+		}
 
-	for idx := 0; idx < fieldsCount; idx++ {
-		field := objType.Field(idx)
-		column := field.Tag.Get("boil")
+		output = append(output, length)
+		remaining -= length
+	}
 
-		if column == "" || column == "-" {
-			continue
-		}
+	return output, nil
+}
+
+// getFingerprint returns a stable identifier for the SQL statement so that callers can cache prepared statements
+func getFingerprint(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
 
-		mapping[column] = field.Name
+// getStructColumns lists every column that the struct has a tag for, in the order the fields are declared. The
+//                  columns of embedded structs are listed where the struct is embedded. See `getStructMapping()`.
+func getStructColumns(objType reflect.Type, tagNames []string) []string {
+	if objType.Kind() == reflect.Ptr {
+		objType = objType.Elem()
+	}
+
+	if objType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	mapping := getStructMapping(objType, tagNames)
+	columns := make([]string, 0, len(mapping.Fields))
+	for _, field := range mapping.Fields {
+		columns = append(columns, field.Column)
+	}
+
+	return columns
+}
+
+// getStructFields gets the fields of the struct based on the database column name -- this should base from the
+//                     `boil` metatdata of the struct, or whichever `tagNames` are given. Columns without a field are
+//                     left out. Maps keyed by column have every column asked for, see `maprows.go`.
+func getStructFields(objType reflect.Type, columns []string, tagNames []string) ([]structField, error) {
+	if objType.Kind() == reflect.Ptr {
+		objType = objType.Elem()
+	}
+
+	if isMapType(objType) {
+		return getMapFields(columns), nil
+	}
+
+	if objType.Kind() != reflect.Struct {
+		return nil, pkgerrors.WithStack(ErrDataNotStruct)
 	}
 
-	fields := make([]string, 0, fieldsCount)
+	mapping := getStructMapping(objType, tagNames)
+	fields := make([]structField, 0, len(columns))
 	// create the list of struct fields
 	for _, column := range columns {
-		field, found := mapping[column]
+		field, found := mapping.field(column)
 		if found {
 			fields = append(fields, field)
 		}
@@ -83,8 +244,8 @@ func getStructFields(objValue reflect.Value, columns []string) ([]string, error)
 	return fields, nil
 }
 
-// isSupportedType checks if the data passed is a valid array or slice data type. Returns a nil `reflect.Type` instance
-//                 if the parameter is not an array or slice.
+// isSupportedType checks if the data passed is a valid array, slice or stream data type. Returns a nil `reflect.Type`
+//                 instance if the parameter is none of those. See `isStreamType()` for the streams accepted.
 //
 //                 who is that var I see
 //                 staring straight back at me?
@@ -101,7 +262,7 @@ func isSupportedType(data interface{}) (reflect.Type, reflect.Value, bool) {
 		dataKind = dataType.Kind()
 	}
 
-	if dataKind != reflect.Array && dataKind != reflect.Slice {
+	if dataKind != reflect.Array && dataKind != reflect.Slice && !isStreamType(dataType) {
 		return nil, reflect.Value{}, false
 	}
 
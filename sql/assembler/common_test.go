@@ -38,3 +38,76 @@ func TestCommon_getBatchingInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestCommon_getBatchSizes(t *testing.T) {
+	tcs := map[string]struct {
+		gvnDataCount int
+		gvnBatchLen  int
+		gvnSizes     []int
+		expSizes     []int
+		expErr       error
+	}{
+		"success__variable_sizes": {
+			gvnDataCount: 10,
+			gvnBatchLen:  4,
+			gvnSizes:     nil,
+			expSizes:     []int{4, 4, 2},
+		},
+		"success__powers_of_two": {
+			gvnDataCount: 13,
+			gvnBatchLen:  100,
+			gvnSizes:     BatchSizesPowersOfTwo(),
+			expSizes:     []int{8, 4, 1},
+		},
+		"success__powers_of_two_capped": {
+			gvnDataCount: 13,
+			gvnBatchLen:  5,
+			gvnSizes:     BatchSizesPowersOfTwo(),
+			expSizes:     []int{4, 4, 4, 1},
+		},
+		"success__configured_sizes_tail": {
+			gvnDataCount: 23,
+			gvnBatchLen:  100,
+			gvnSizes:     []int{5, 10},
+			expSizes:     []int{10, 10, 1, 1, 1},
+		},
+		"success__configured_sizes_some_too_large": {
+			gvnDataCount: 23,
+			gvnBatchLen:  8,
+			gvnSizes:     []int{5, 10},
+			expSizes:     []int{5, 5, 5, 5, 1, 1, 1},
+		},
+		"failure__configured_sizes_too_large": {
+			gvnDataCount: 23,
+			gvnBatchLen:  8,
+			gvnSizes:     []int{10, 20},
+			expErr:       ErrBatchSizes,
+		},
+		"failure__configured_sizes_not_positive": {
+			gvnDataCount: 23,
+			gvnBatchLen:  8,
+			gvnSizes:     []int{4, 0},
+			expErr:       ErrBatchSizes,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// When
+			sizes, err := getBatchSizes(
+				tc.gvnDataCount,
+				tc.gvnBatchLen,
+				tc.gvnSizes,
+			)
+
+			// Then
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expSizes, sizes)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package assembler
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+/**
+ * Question: how are the errors of the drivers told apart without importing them?
+ * Answer: SQLBoiler is driver-agnostic and teams are split between `lib/pq` and `pgx`. Both errors give their code
+ *         through `SQLState()`, which is how they are found in the chain. The rest of the fields from the Postgres
+ *         protocol are read off the error struct by name, e.g. `Table` for `lib/pq` and `TableName` for `pgx`, into
+ *         `pgError` and we work from there.
+ *
+ * The `Key (...)=(...)` detail is meant for humans, so matching it against the rows is best-effort: each value is
+ * formatted the way Postgres would likely print it and compared as text.
+ */
+
+const (
+	pgClassIntegrityViolation = "23"
+	pgCodeNotNullViolation    = "23502"
+)
+
+var pgKeyDetailPattern = regexp.MustCompile(
+	`^Key \((.+?)\)=\((.*)\) (?:already exists|is not present in table|is still referenced from table|conflicts with)`,
+)
+
+// pgError represents the parts of a Postgres error that we care about regardless of the driver that raised it
+type pgError struct {
+	Code       string
+	Message    string
+	Detail     string
+	Table      string
+	Column     string
+	Constraint string
+}
+
+// sqlStateError represents a Postgres error from any driver, e.g. `*pq.Error` or `*pgconn.PgError`
+type sqlStateError interface {
+	error
+	SQLState() string
+}
+
+// asPgError looks for a Postgres error from any driver in the chain
+func asPgError(err error) (pgError, bool) {
+	var stateErr sqlStateError
+	if !errors.As(err, &stateErr) {
+		return pgError{}, false
+	}
+
+	return pgError{
+		Code:       stateErr.SQLState(),
+		Message:    getErrorField(stateErr, "Message"),
+		Detail:     getErrorField(stateErr, "Detail"),
+		Table:      getErrorField(stateErr, "TableName", "Table"),
+		Column:     getErrorField(stateErr, "ColumnName", "Column"),
+		Constraint: getErrorField(stateErr, "ConstraintName", "Constraint"),
+	}, true
+}
+
+// getErrorField reads the first of the string fields the error struct has, or an empty string when it has none of them
+func getErrorField(err error, names ...string) string {
+	reflected := reflect.ValueOf(err)
+	for reflected.Kind() == reflect.Ptr {
+		if reflected.IsNil() {
+			return ""
+		}
+		reflected = reflected.Elem()
+	}
+	if reflected.Kind() != reflect.Struct {
+		return ""
+	}
+
+	for _, name := range names {
+		field := reflected.FieldByName(name)
+		if field.IsValid() && field.Kind() == reflect.String {
+			return field.String()
+		}
+	}
+
+	return ""
+}
+
+// enrichError turns constraint violations into a `ConstraintError` pointing at the rows of the batch that match the
+//             violation. Anything else is returned as it is.
+func enrichError(group QueryGroup, err error) error {
+	pgErr, ok := asPgError(err)
+	if !ok || !strings.HasPrefix(pgErr.Code, pgClassIntegrityViolation) {
+		return err
+	}
+
+	constraintErr := ConstraintError{
+		Batch:      group.Batch,
+		DataIndex:  -1,
+		Table:      pgErr.Table,
+		Column:     pgErr.Column,
+		Constraint: pgErr.Constraint,
+		Code:       pgErr.Code,
+		Err:        err,
+	}
+
+	var (
+		columns []string
+		values  string
+	)
+	switch match := pgKeyDetailPattern.FindStringSubmatch(pgErr.Detail); {
+	case match != nil:
+		columns = strings.Split(match[1], ", ")
+		for idx, column := range columns {
+			columns[idx] = strings.Trim(column, `"`)
+		}
+		values = match[2]
+		constraintErr.Column = strings.Join(columns, ", ")
+
+	case pgErr.Code == pgCodeNotNullViolation && pgErr.Column != "":
+		columns = []string{pgErr.Column}
+
+	default:
+		return constraintErr
+	}
+
+	for _, idx := range findRows(group, columns, values, pgErr.Code == pgCodeNotNullViolation) {
+		constraintErr.DataIndices = append(constraintErr.DataIndices, group.DataIndex(idx))
+	}
+	if len(constraintErr.DataIndices) > 0 {
+		constraintErr.DataIndex = constraintErr.DataIndices[0]
+	}
+
+	return constraintErr
+}
+
+// findRows finds every item whose values for the columns read the same as `values`, or are all NULL if `null` is
+//          asked for instead
+func findRows(group QueryGroup, columns []string, values string, null bool) []int {
+	items := group.items
+	if len(items) == 0 {
+		return nil
+	}
+
+	fields, err := getStructFields(group.itemType(), columns, group.tagNames)
+	if err != nil || len(fields) != len(columns) {
+		return nil
+	}
+
+	var output []int
+
+	for idx, item := range items {
+		if item.Kind() == reflect.Ptr {
+			item = item.Elem()
+		}
+
+		formatted := make([]string, 0, len(fields))
+		nulls := 0
+		for _, field := range fields {
+			value, ok := formatValue(getFieldValue(item, field))
+			if !ok {
+				nulls++
+			}
+			formatted = append(formatted, value)
+		}
+
+		if null && nulls == len(fields) || !null && nulls == 0 && strings.Join(formatted, ", ") == values {
+			output = append(output, idx)
+		}
+	}
+
+	return output
+}
+
+// formatValue formats the value the way Postgres would print it in an error detail. Returns false for NULL.
+func formatValue(value interface{}) (string, bool) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		output, err := valuer.Value()
+		if err != nil {
+			return "", false
+		}
+		value = output
+	}
+
+	reflected := reflect.ValueOf(value)
+	for reflected.Kind() == reflect.Ptr {
+		if reflected.IsNil() {
+			return "", false
+		}
+		reflected = reflected.Elem()
+	}
+
+	if !reflected.IsValid() {
+		return "", false
+	}
+
+	switch output := reflected.Interface().(type) {
+	case []byte:
+		return string(output), true
+	case bool:
+		if output {
+			return "t", true
+		}
+		return "f", true
+	default:
+		return fmt.Sprint(output), true
+	}
+}
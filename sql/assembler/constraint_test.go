@@ -0,0 +1,219 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pqError has the same shape as `*pq.Error`, which the package does not depend on
+type pqError struct {
+	Code       string
+	Message    string
+	Detail     string
+	Table      string
+	Column     string
+	Constraint string
+}
+
+func (e *pqError) Error() string {
+	return "pq: " + e.Message
+}
+
+func (e *pqError) SQLState() string {
+	return e.Code
+}
+
+// pgconnError has the same shape as `*pgconn.PgError`, which the package does not depend on
+type pgconnError struct {
+	Code           string
+	Message        string
+	Detail         string
+	TableName      string
+	ColumnName     string
+	ConstraintName string
+}
+
+func (e *pgconnError) Error() string {
+	return fmt.Sprintf("ERROR: %s (SQLSTATE %s)", e.Message, e.Code)
+}
+
+func (e *pgconnError) SQLState() string {
+	return e.Code
+}
+
+func TestConstraint_enrichError(t *testing.T) {
+	type SampleTable struct {
+		ID           int64   `boil:"id"`
+		AssetID      string  `boil:"asset_id"`
+		SubstationID *int64  `boil:"substation_id"`
+		Name         *string `boil:"name"`
+	}
+
+	substationID := int64(12)
+	name := "Substation"
+	data := []*SampleTable{
+		{ID: 1, AssetID: "DXSS0001", SubstationID: nil, Name: &name},
+		{ID: 2, AssetID: "DXSS0002", SubstationID: &substationID, Name: nil},
+		{ID: 3, AssetID: "DXSS0003", SubstationID: nil, Name: &name},
+		{ID: 4, AssetID: "DXSS0003", SubstationID: nil, Name: &name},
+	}
+
+	items := make([]reflect.Value, 0, len(data))
+	for _, row := range data {
+		items = append(items, reflect.ValueOf(row))
+	}
+	group := QueryGroup{Batch: 2, DataStart: 10, DataEnd: 14, items: items}
+
+	tcs := map[string]struct {
+		gvnErr         error
+		expConstraint  bool
+		expDataIndex   int
+		expDataIndices []int
+		expColumn      string
+	}{
+		"success__pq_unique_violation": {
+			gvnErr: &pqError{
+				Code:       "23505",
+				Detail:     "Key (asset_id)=(DXSS0003) already exists.",
+				Constraint: "sample_asset_id_key",
+			},
+			expConstraint:  true,
+			expDataIndex:   12,
+			expDataIndices: []int{12, 13},
+			expColumn:      "asset_id",
+		},
+		"success__pgx_multi_column_unique_violation": {
+			gvnErr: &pgconnError{
+				Code:           "23505",
+				Detail:         "Key (id, asset_id)=(2, DXSS0002) already exists.",
+				ConstraintName: "sample_pkey",
+			},
+			expConstraint:  true,
+			expDataIndex:   11,
+			expDataIndices: []int{11},
+			expColumn:      "id, asset_id",
+		},
+		"success__foreign_key_violation": {
+			gvnErr: &pqError{
+				Code:       "23503",
+				Detail:     "Key (substation_id)=(12) is not present in table \"substations\".",
+				Constraint: "sample_substation_id_fkey",
+			},
+			expConstraint:  true,
+			expDataIndex:   11,
+			expDataIndices: []int{11},
+			expColumn:      "substation_id",
+		},
+		"success__not_null_violation": {
+			gvnErr: &pgconnError{
+				Code:       "23502",
+				ColumnName: "name",
+			},
+			expConstraint:  true,
+			expDataIndex:   11,
+			expDataIndices: []int{11},
+			expColumn:      "name",
+		},
+		"success__no_matching_row": {
+			gvnErr: &pqError{
+				Code:       "23505",
+				Detail:     "Key (asset_id)=(DXSS9999) already exists.",
+				Constraint: "sample_asset_id_key",
+			},
+			expConstraint: true,
+			expDataIndex:  -1,
+			expColumn:     "asset_id",
+		},
+		"success__not_a_constraint_violation": {
+			gvnErr:        &pqError{Code: "40001"},
+			expConstraint: false,
+		},
+		"success__not_a_postgres_error": {
+			gvnErr:        errors.New("connection reset"),
+			expConstraint: false,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// When
+			err := enrichError(group, tc.gvnErr)
+
+			// Then
+			var constraintErr ConstraintError
+			if !tc.expConstraint {
+				require.False(t, errors.As(err, &constraintErr))
+				require.Equal(t, tc.gvnErr, err)
+				return
+			}
+
+			require.True(t, errors.As(err, &constraintErr))
+			require.Equal(t, 2, constraintErr.Batch)
+			require.Equal(t, tc.expDataIndex, constraintErr.DataIndex)
+			require.Equal(t, tc.expDataIndices, constraintErr.DataIndices)
+			require.Contains(t, err.Error(), fmt.Sprintf("batch 2 (data %d", tc.expDataIndex))
+			require.Equal(t, tc.expColumn, constraintErr.Column)
+			require.True(t, errors.Is(err, tc.gvnErr))
+		})
+	}
+}
+
+func TestConstraint_asPgError(t *testing.T) {
+	expected := pgError{
+		Code:       "23505",
+		Message:    "duplicate key value",
+		Detail:     "Key (id)=(1) already exists.",
+		Table:      "sample",
+		Column:     "id",
+		Constraint: "sample_pkey",
+	}
+
+	tcs := map[string]struct {
+		gvnErr error
+		expOk  bool
+	}{
+		"success__pq": {
+			gvnErr: &pqError{
+				Code:       "23505",
+				Message:    "duplicate key value",
+				Detail:     "Key (id)=(1) already exists.",
+				Table:      "sample",
+				Column:     "id",
+				Constraint: "sample_pkey",
+			},
+			expOk: true,
+		},
+		"success__pgx_wrapped": {
+			gvnErr: fmt.Errorf("batch: %w", &pgconnError{
+				Code:           "23505",
+				Message:        "duplicate key value",
+				Detail:         "Key (id)=(1) already exists.",
+				TableName:      "sample",
+				ColumnName:     "id",
+				ConstraintName: "sample_pkey",
+			}),
+			expOk: true,
+		},
+		"failure__no_sql_state": {
+			gvnErr: errors.New("connection reset"),
+			expOk:  false,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// When
+			pgErr, ok := asPgError(tc.gvnErr)
+
+			// Then
+			require.Equal(t, tc.expOk, ok)
+			if tc.expOk {
+				require.Equal(t, expected, pgErr)
+			}
+		})
+	}
+}
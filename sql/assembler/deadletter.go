@@ -0,0 +1,247 @@
+package assembler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+/**
+ * Question: which rows end up as dead letters?
+ * Answer: every row the result reports as not written once the execution is over -- after the retries, so transient
+ *         failures that went away are not reported. Rows of a failed batch are all sent along with the batch's error
+ *         since we cannot tell which of them is at fault; turn on `Bisect` to only get the rows that fail on their own.
+ *
+ *         Rows that were never attempted are not dead letters, nothing is wrong with them: the batches after the first
+ *         failure of `Exec()`, or those cancelled by it in `ExecParallel()`. Neither are the rows rolled back along
+ *         with the failing batch of `TxAllOrNothing` -- only that batch is sent, and the error tells that nothing else
+ *         was committed either, so the rest of the data can be run again as it is.
+ *
+ *         The rows are stored by their columns, read through the same tags as the batch, so that they can be replayed
+ *         into the same table later.
+ */
+
+// DeadLetter represents a row that could not be written
+type DeadLetter struct {
+	Row       interface{}
+	DataIndex int
+	Batch     int
+	Err       error
+
+	// tagNames are the struct tags the columns of the row are read from, see `TagNames()`
+	tagNames []string
+}
+
+// DeadLetterSink keeps the rows that could not be written somewhere they can be looked at and replayed from
+type DeadLetterSink interface {
+	Send(ctx context.Context, letters []DeadLetter) error
+}
+
+// DeadLetterFunc is a hook receiving the rows that could not be written one at a time
+type DeadLetterFunc func(ctx context.Context, letter DeadLetter) error
+
+// Send implements `DeadLetterSink`
+func (fn DeadLetterFunc) Send(ctx context.Context, letters []DeadLetter) error {
+	for _, letter := range letters {
+		if err := fn(ctx, letter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deadLetterRecord represents a dead letter the way it is written by the built-in sinks
+type deadLetterRecord struct {
+	Batch     int       `boil:"batch" json:"batch"`
+	DataIndex int       `boil:"data_index" json:"data_index"`
+	Error     string    `boil:"error" json:"error"`
+	Row       string    `boil:"row" json:"-"`
+	FailedAt  time.Time `boil:"failed_at" json:"failed_at"`
+}
+
+// newDeadLetterRecord prepares the dead letter for writing, with the row as a JSON object of its columns
+func newDeadLetterRecord(letter DeadLetter) (*deadLetterRecord, error) {
+	row, err := json.Marshal(getRowColumns(letter.Row, letter.tagNames))
+	if err != nil {
+		return nil, pkgerrors.WithStack(err)
+	}
+
+	return &deadLetterRecord{
+		Batch:     letter.Batch,
+		DataIndex: letter.DataIndex,
+		Error:     fmt.Sprint(letter.Err),
+		Row:       string(row),
+		FailedAt:  GetCurrentTime(),
+	}, nil
+}
+
+// JSONLinesSink writes every dead letter as a line of JSON. It is safe to use from multiple goroutines.
+type JSONLinesSink struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewJSONLinesSink creates a new instance writing to `writer`, usually a file opened for appending
+func NewJSONLinesSink(writer io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{writer: writer}
+}
+
+// Send implements `DeadLetterSink`. Each line holds `batch`, `data_index`, `error`, `failed_at` and `row`.
+func (sink *JSONLinesSink) Send(ctx context.Context, letters []DeadLetter) error {
+	lines := make([]byte, 0)
+	for _, letter := range letters {
+		record, err := newDeadLetterRecord(letter)
+		if err != nil {
+			return err
+		}
+
+		line, err := json.Marshal(struct {
+			*deadLetterRecord
+			Row json.RawMessage `json:"row"`
+		}{record, json.RawMessage(record.Row)})
+		if err != nil {
+			return pkgerrors.WithStack(err)
+		}
+
+		lines = append(append(lines, line...), '\n')
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	_, err := sink.writer.Write(lines)
+	return pkgerrors.WithStack(err)
+}
+
+// TableSink writes the dead letters into a table of the database, which is expected to look like:
+//
+//           CREATE TABLE "dead_letters" (
+//               "id"         BIGSERIAL PRIMARY KEY,
+//               "batch"      INTEGER NOT NULL,
+//               "data_index" INTEGER NOT NULL,
+//               "error"      TEXT NOT NULL,
+//               "row"        JSONB NOT NULL,
+//               "failed_at"  TIMESTAMPTZ NOT NULL
+//           );
+//
+//           Give it the database rather than the transaction the rows failed in, which might be rolled back.
+type TableSink struct {
+	db    boil.ContextExecutor
+	table string
+}
+
+// NewTableSink creates a new instance writing to `table`
+func NewTableSink(db boil.ContextExecutor, table string) *TableSink {
+	return &TableSink{db: db, table: table}
+}
+
+// Send implements `DeadLetterSink`
+func (sink *TableSink) Send(ctx context.Context, letters []DeadLetter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+
+	records := make([]*deadLetterRecord, 0, len(letters))
+	for _, letter := range letters {
+		record, err := newDeadLetterRecord(letter)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+	}
+
+	op, err := NewBulkInsert(records, sink.table, []string{"batch", "data_index", "error", "row", "failed_at"})
+	if err != nil {
+		return err
+	}
+
+	_, err = op.Exec(ctx, sink.db)
+	return err
+}
+
+// getRowColumns maps the columns of the row to their values, read through `tagNames`
+func getRowColumns(row interface{}, tagNames []string) map[string]interface{} {
+	value := reflect.ValueOf(row)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	if isMapType(value.Type()) {
+		return getMapRow(value)
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := getStructMapping(value.Type(), tagNames).Fields
+	output := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		output[field.Column] = getFieldValue(value, field)
+	}
+
+	return output
+}
+
+// deadLetters lists every row that did not make it, in the order of the data
+func (result ExecResult) deadLetters() []DeadLetter {
+	letters := make([]DeadLetter, 0, len(result.RowErrors))
+	for _, batchErr := range result.Failed {
+		for idx, item := range batchErr.group.items {
+			letters = append(letters, DeadLetter{
+				Row:       item.Interface(),
+				DataIndex: batchErr.group.DataIndex(idx),
+				Batch:     batchErr.Batch,
+				Err:       batchErr.Err,
+				tagNames:  batchErr.group.tagNames,
+			})
+		}
+	}
+
+	for _, rowErr := range result.RowErrors {
+		letters = append(letters, DeadLetter{
+			Row:       rowErr.Row,
+			DataIndex: rowErr.DataIndex,
+			Batch:     rowErr.Batch,
+			Err:       rowErr.Err,
+			tagNames:  rowErr.tagNames,
+		})
+	}
+
+	sort.SliceStable(letters, func(i, j int) bool {
+		return letters[i].DataIndex < letters[j].DataIndex
+	})
+
+	return letters
+}
+
+// sendDeadLetters hands the rows that did not make it over to the sink, if there is one. A failing sink is reported
+//                 along with the error of the execution.
+func sendDeadLetters(ctx context.Context, sink DeadLetterSink, result ExecResult, err error) (ExecResult, error) {
+	if sink == nil {
+		return result, err
+	}
+
+	letters := result.deadLetters()
+	if len(letters) == 0 {
+		return result, err
+	}
+
+	if sinkErr := sink.Send(ctx, letters); sinkErr != nil {
+		return result, errors.Join(err, fmt.Errorf("%w: %w", ErrDeadLetter, sinkErr))
+	}
+
+	return result, err
+}
@@ -0,0 +1,321 @@
+package assembler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"code.in.spdigital.sg/sp-digital/athena/db/pg"
+	"code.in.spdigital.sg/sp-digital/athena/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecResult_deadLetters(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	errBatch := errors.New("batch failed")
+	errRow := errors.New("row failed")
+
+	tcs := map[string]struct {
+		gvnSortBy  []string
+		gvnFailed  []int
+		gvnRowErrs []int
+		expIndices []int
+	}{
+		"success__nothing_failed": {
+			gvnSortBy:  nil,
+			gvnFailed:  nil,
+			gvnRowErrs: nil,
+			expIndices: []int{},
+		},
+		"failure__batches_and_rows": {
+			gvnSortBy:  nil,
+			gvnFailed:  []int{1},
+			gvnRowErrs: []int{0},
+			expIndices: []int{0, 2, 3},
+		},
+		"failure__sorted_batches": {
+			gvnSortBy:  []string{"col_01"},
+			gvnFailed:  []int{0},
+			gvnRowErrs: nil,
+			expIndices: []int{3, 4},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []*SampleTable{
+				{ID: 0, Col01: "e"},
+				{ID: 1, Col01: "d"},
+				{ID: 2, Col01: "c"},
+				{ID: 3, Col01: "b"},
+				{ID: 4, Col01: "a"},
+			}
+
+			op, err := NewBulkInsert(data, "sample_table", []string{"id", "col_01"})
+			require.NoError(t, err)
+			op.SortBy = tc.gvnSortBy
+			op.BatchSizes = []int{2, 1}
+
+			groups, err := op.Queries()
+			require.NoError(t, err)
+
+			result := ExecResult{}
+			for _, idx := range tc.gvnFailed {
+				_ = result.fail(groups[idx], errBatch)
+			}
+			for _, idx := range tc.gvnRowErrs {
+				result.RowErrors = append(result.RowErrors, RowError{DataIndex: idx, Row: data[idx], Err: errRow})
+			}
+
+			// When
+			letters := result.deadLetters()
+
+			// Then
+			indices := make([]int, 0, len(letters))
+			for _, letter := range letters {
+				require.Same(t, data[letter.DataIndex], letter.Row)
+				indices = append(indices, letter.DataIndex)
+			}
+			require.Equal(t, tc.expIndices, indices)
+		})
+	}
+}
+
+func TestBulkInsert_Exec_DeadLetters(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	tcs := map[string]struct {
+		gvnParallel        bool
+		gvnContinueOnError bool
+		gvnDelay           time.Duration
+		gvnBadRow          int
+		expIndices         []int
+	}{
+		"failure__exec_stops_at_first": {
+			gvnParallel: false,
+			gvnDelay:    0,
+			gvnBadRow:   3,
+			expIndices:  []int{2, 3},
+		},
+		"failure__parallel_cancelled": {
+			gvnParallel: true,
+			gvnDelay:    time.Minute,
+			gvnBadRow:   0,
+			expIndices:  []int{0, 1},
+		},
+		"failure__parallel_continue_on_error": {
+			gvnParallel:        true,
+			gvnContinueOnError: true,
+			gvnDelay:           time.Millisecond,
+			gvnBadRow:          5,
+			expIndices:         []int{4, 5},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			ctx := context.Background()
+
+			data := make([]*SampleTable, 0, 8)
+			for idx := 0; idx < 8; idx++ {
+				data = append(data, &SampleTable{ID: int64(idx), Col01: "DataRow"})
+			}
+			data[tc.gvnBadRow].Col01 = "BadRow"
+
+			// the healthy batches take `gvnDelay`, which the failing one cancels when not continuing
+			fail := failOnValue("BadRow")
+			exec := &fakeExecutor{
+				delay: tc.gvnDelay,
+				wait:  func(args []interface{}) bool { return fail(args) == nil },
+				fail:  fail,
+			}
+
+			var (
+				mutex   sync.Mutex
+				indices []int
+			)
+			sink := DeadLetterFunc(func(ctx context.Context, letter DeadLetter) error {
+				require.NoError(t, ctx.Err())
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				indices = append(indices, letter.DataIndex)
+				return nil
+			})
+
+			op, err := NewBulkInsert(data, "sample_table", []string{"id", "col_01"})
+			require.NoError(t, err)
+			op.BatchSizes = []int{2}
+			op.DeadLetters = sink
+
+			// When
+			var result ExecResult
+			if tc.gvnParallel {
+				result, err = op.ExecParallel(ctx, SharedConns(exec), ParallelOptions{
+					Concurrency:     2,
+					ContinueOnError: tc.gvnContinueOnError,
+				})
+			} else {
+				result, err = op.Exec(ctx, exec)
+			}
+
+			// Then
+			require.Error(t, err)
+			require.Len(t, result.Failed, 1)
+			require.Equal(t, tc.expIndices, indices)
+		})
+	}
+}
+
+func TestJSONLinesSink_Send(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+		Col02 string
+	}
+
+	// Given
+	buffer := &bytes.Buffer{}
+	sink := NewJSONLinesSink(buffer)
+
+	letters := []DeadLetter{
+		{Row: &SampleTable{ID: 1, Col01: "DataRow__1", Col02: "skipped"}, DataIndex: 1, Batch: 0, Err: errors.New("bad")},
+		{Row: SampleTable{ID: 7, Col01: "DataRow__7"}, DataIndex: 7, Batch: 3, Err: errors.New("worse")},
+	}
+
+	// When
+	err := sink.Send(context.Background(), letters)
+
+	// Then
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	for idx, line := range lines {
+		var record struct {
+			Batch     int                    `json:"batch"`
+			DataIndex int                    `json:"data_index"`
+			Error     string                 `json:"error"`
+			Row       map[string]interface{} `json:"row"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		require.Equal(t, letters[idx].Batch, record.Batch)
+		require.Equal(t, letters[idx].DataIndex, record.DataIndex)
+		require.Equal(t, letters[idx].Err.Error(), record.Error)
+		require.Len(t, record.Row, 2)
+		require.Equal(t, float64(letters[idx].DataIndex), record.Row["id"])
+	}
+}
+
+func TestBulkInsert_ExecTx_DeadLetters(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+	tableName := "sample"
+	deadLettersTableName := "dead_letters"
+	createSQL := "" +
+		"CREATE TABLE \"" + tableName + "\" (\n" +
+		"    \"id\" BIGINT PRIMARY KEY,\n" +
+		"    \"col_01\" TEXT NOT NULL CHECK (\"col_01\" <> '')\n" +
+		");\n" +
+		"CREATE TABLE \"" + deadLettersTableName + "\" (\n" +
+		"    \"id\" BIGSERIAL PRIMARY KEY,\n" +
+		"    \"batch\" INTEGER NOT NULL,\n" +
+		"    \"data_index\" INTEGER NOT NULL,\n" +
+		"    \"error\" TEXT NOT NULL,\n" +
+		"    \"row\" JSONB NOT NULL,\n" +
+		"    \"failed_at\" TIMESTAMPTZ NOT NULL\n" +
+		");"
+
+	tcs := map[string]struct {
+		gvnMode    TxMode
+		gvnBisect  bool
+		expIndices []int
+	}{
+		"failure__whole_batch": {
+			gvnMode:    TxSavepoint,
+			gvnBisect:  false,
+			expIndices: []int{4, 5, 6, 7},
+		},
+		"failure__bisected_row": {
+			gvnMode:    TxSavepoint,
+			gvnBisect:  true,
+			expIndices: []int{5},
+		},
+		"failure__all_or_nothing": {
+			// the first batch is rolled back too, but only the failing one is sent
+			gvnMode:    TxAllOrNothing,
+			gvnBisect:  false,
+			expIndices: []int{4, 5, 6, 7},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			testutil.WithTxDB(t, func(tx pg.BeginnerExecutor) {
+				// Given
+				ctx := context.Background()
+
+				_, err := tx.ExecContext(ctx, createSQL)
+				require.NoError(t, err)
+
+				data := make([]*SampleTable, 0, 10)
+				for idx := 0; idx < 10; idx++ {
+					data = append(data, &SampleTable{ID: int64(idx), Col01: "DataRow"})
+				}
+				data[5].Col01 = ""
+
+				op, err := NewBulkInsert(data, tableName, []string{"id", "col_01"})
+				require.NoError(t, err)
+				op.BatchSizes = []int{4}
+
+				// When
+				_, err = op.ExecTx(ctx, tx, ExecOptions{
+					Mode:        tc.gvnMode,
+					Bisect:      tc.gvnBisect,
+					DeadLetters: NewTableSink(tx, deadLettersTableName),
+				})
+
+				// Then
+				if tc.gvnMode == TxAllOrNothing {
+					var batchErr BatchError
+					require.ErrorAs(t, err, &batchErr)
+				} else {
+					require.ErrorIs(t, err, ErrBatchFailed)
+				}
+
+				rows, err := tx.QueryContext(ctx, "SELECT \"data_index\", \"row\"->>'id' FROM \""+deadLettersTableName+"\" ORDER BY \"data_index\"")
+				require.NoError(t, err)
+				defer rows.Close()
+
+				indices := make([]int, 0)
+				for rows.Next() {
+					var (
+						index int
+						id    string
+					)
+					require.NoError(t, rows.Scan(&index, &id))
+					indices = append(indices, index)
+				}
+				require.NoError(t, rows.Err())
+				require.Equal(t, tc.expIndices, indices)
+			})
+		})
+	}
+}
@@ -1,12 +1,227 @@
 package assembler
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
 
 var (
 	// ErrDataEmpty when the data is an empty array
 	ErrDataEmpty = errors.New("must be a non-empty array")
-	// ErrDataNotArray when the data is not an array
-	ErrDataNotArray = errors.New("must be an array or slice")
-	// ErrDataNotStruct when data items are not struct or pointer to struct
-	ErrDataNotStruct = errors.New("object must be a struct or pointer to a struct")
+	// ErrDataNotArray when the data is neither an array nor a stream
+	ErrDataNotArray = errors.New("must be an array, slice, channel or iterator")
+	// ErrDataNotStruct when data items are not struct or pointer to struct, nor maps keyed by column
+	ErrDataNotStruct = errors.New("object must be a struct, a pointer to a struct or a map keyed by column")
+	// ErrDataNil when an item of the data is nil, see `ItemError`
+	ErrDataNil = errors.New("item must not be nil")
+	// ErrDataMixed when the items of the data are not all of the same type, see `ItemError`
+	ErrDataMixed = errors.New("items must all be of the same type")
+	// ErrBatchFailed when at least one of the batches could not be written
+	ErrBatchFailed = errors.New("one or more batches failed")
+	// ErrBatchSizes when none of the `BatchSizes` can be used, see `getBatchSizes()`
+	ErrBatchSizes = errors.New("unusable batch sizes")
+	// ErrSortColumn when a column to sort by is not on the struct
+	ErrSortColumn = errors.New("sort column not found in struct")
+	// ErrWriterClosed when rows are written to a `Writer` that has been closed
+	ErrWriterClosed = errors.New("writer is closed")
+	// ErrDeadLetter when the rows that could not be written could not be handed over to the dead-letter sink either
+	ErrDeadLetter = errors.New("dead letters could not be sent")
+	// ErrScanColumn when a column of the rows being scanned has no struct field to go into
+	ErrScanColumn = errors.New("column not found in struct")
+	// ErrMissingKey when a map row has no key for one of the columns, see `MissingKeyError`
+	ErrMissingKey = errors.New("column missing from row")
+	// ErrMapColumns when the columns of a stream of maps are to be inferred, which cannot be done without reading it
+	ErrMapColumns = errors.New("columns of streamed maps must be named")
+	// ErrCopyDefault when a row given as values has `DEFAULT` in it, which `COPY` has no way of saying. See `Values()`
+	ErrCopyDefault = errors.New("DEFAULT cannot go through COPY")
+	// ErrHookFailed when one of the hooks of a batch failed, see `hooks.go`
+	ErrHookFailed = errors.New("hook failed")
+	// ErrAfterHookFailed when one of the `After*` hooks of a batch failed once its rows were written, see `hooks.go`
+	ErrAfterHookFailed = errors.New("after hook failed")
+	// ErrBindMismatch when the `RETURNING` rows of a batch are not one for each of its rows, see `writeBack()`
+	ErrBindMismatch = errors.New("returned rows do not line up with the batch")
+	// ErrBindDoNothing when binding the `RETURNING` rows of an upsert with nothing to update, see `ExecAndBind()`
+	ErrBindDoNothing = errors.New("rows of ON CONFLICT DO NOTHING cannot be bound")
+	// ErrColumnInvalid when the columns asked for do not line up with the struct, see `ColumnError`
+	ErrColumnInvalid = errors.New("invalid columns")
+
+	// errSavepoint when the savepoint statements themselves fail, after which the transaction is unusable
+	errSavepoint = errors.New("savepoint failed")
 )
+
+// BatchError when a batch could not be written. Holds the range of data it covers, along with where each row sits in
+// the data given when they were sorted. See `Indices()`.
+type BatchError struct {
+	Batch       int
+	DataStart   int
+	DataEnd     int
+	DataIndices []int
+	Attempts    int
+	Err         error
+
+	// group is the batch itself, kept around to be able to tell which rows did not make it
+	group QueryGroup
+}
+
+// Error implements `error`
+func (e BatchError) Error() string {
+	if e.DataIndices != nil {
+		return fmt.Sprintf("batch %d (data %s): %v", e.Batch, formatIndices(e.DataIndices), e.Err)
+	}
+
+	return fmt.Sprintf("batch %d (data %d..%d): %v", e.Batch, e.DataStart, e.DataEnd, e.Err)
+}
+
+// Indices returns where the rows of the batch sit in the data given, in the order they were sent
+func (e BatchError) Indices() []int {
+	return getDataIndices(e.DataStart, e.DataEnd, e.DataIndices)
+}
+
+// Unwrap returns the error of the batch
+func (e BatchError) Unwrap() error {
+	return e.Err
+}
+
+// ItemError when an item of the data cannot be read from. Holds where it sits in the data and its type, along with the
+// type expected of it when the items are mixed.
+type ItemError struct {
+	DataIndex int
+	Type      string
+	Expected  string
+	Err       error
+}
+
+// Error implements `error`
+func (e ItemError) Error() string {
+	if e.Expected != "" {
+		return fmt.Sprintf("data %d: %v: got %s, expected %s", e.DataIndex, e.Err, e.Type, e.Expected)
+	}
+
+	return fmt.Sprintf("data %d (%s): %v", e.DataIndex, e.Type, e.Err)
+}
+
+// Unwrap returns what is wrong with the item
+func (e ItemError) Unwrap() error {
+	return e.Err
+}
+
+// RowError when a single row could not be written. Holds the row itself and where it sits in the data.
+type RowError struct {
+	Batch     int
+	DataIndex int
+	Row       interface{}
+	Err       error
+
+	// tagNames are the struct tags the columns of the row are read from, kept around for the dead letters
+	tagNames []string
+}
+
+// Error implements `error`
+func (e RowError) Error() string {
+	return fmt.Sprintf("batch %d (data %d): %v", e.Batch, e.DataIndex, e.Err)
+}
+
+// Unwrap returns the error of the row
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// ConstraintError when a batch violates a constraint. `DataIndices` holds every row of the batch matching the violation,
+// e.g. all the rows sharing a duplicate key. `DataIndex` points to the first of them, or is -1 when no row could be
+// matched.
+type ConstraintError struct {
+	Batch       int
+	DataIndex   int
+	DataIndices []int
+	Table       string
+	Column      string
+	Constraint  string
+	Code        string
+	Err         error
+}
+
+// Error implements `error`
+func (e ConstraintError) Error() string {
+	data := fmt.Sprint(e.DataIndex)
+	if len(e.DataIndices) > 1 {
+		data = formatIndices(e.DataIndices)
+	}
+
+	return fmt.Sprintf(
+		"batch %d (data %s): constraint %q on %q (%s): %v",
+		e.Batch,
+		data,
+		e.Constraint,
+		e.Column,
+		e.Code,
+		e.Err,
+	)
+}
+
+// Unwrap returns the error raised by the database driver
+func (e ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// ColumnError when some of the columns asked for have no `boil` tag in the struct, or are asked for more than once.
+// `Suggestions` holds the closest tagged column for each unknown column that has one. `Conflicts` holds the fields
+// claiming the same column at the same level of embedded structs, which is why none of them is used. `ReadOnly` holds
+// the columns tagged `readonly` that were asked to be written.
+type ColumnError struct {
+	Type        string
+	Unknown     []string
+	Suggestions map[string]string
+	Duplicates  []string
+	Conflicts   map[string][]string
+	ReadOnly    []string
+}
+
+// Error implements `error`
+func (e ColumnError) Error() string {
+	problems := make([]string, 0, 4)
+	if len(e.Unknown) > 0 {
+		unknown := make([]string, 0, len(e.Unknown))
+		for _, column := range e.Unknown {
+			if suggestion, ok := e.Suggestions[column]; ok {
+				column = fmt.Sprintf("%q (did you mean %q?)", column, suggestion)
+			} else {
+				column = fmt.Sprintf("%q", column)
+			}
+			unknown = append(unknown, column)
+		}
+		problems = append(problems, "unknown columns "+strings.Join(unknown, ", "))
+	}
+	if len(e.Duplicates) > 0 {
+		problems = append(problems, fmt.Sprintf("duplicate columns %q", e.Duplicates))
+	}
+	if len(e.Conflicts) > 0 {
+		conflicts := make([]string, 0, len(e.Conflicts))
+		for _, column := range slices.Sorted(maps.Keys(e.Conflicts)) {
+			conflicts = append(conflicts, fmt.Sprintf("%q (%s)", column, strings.Join(e.Conflicts[column], ", ")))
+		}
+		problems = append(problems, "conflicting columns "+strings.Join(conflicts, ", "))
+	}
+	if len(e.ReadOnly) > 0 {
+		problems = append(problems, fmt.Sprintf("read-only columns %q", e.ReadOnly))
+	}
+
+	return fmt.Sprintf("%v for %s: %s", ErrColumnInvalid, e.Type, strings.Join(problems, "; "))
+}
+
+// Unwrap returns `ErrColumnInvalid`
+func (e ColumnError) Unwrap() error {
+	return ErrColumnInvalid
+}
+
+// formatIndices lists the data indices for the error messages
+func formatIndices(indices []int) string {
+	output := make([]string, 0, len(indices))
+	for _, idx := range indices {
+		output = append(output, fmt.Sprint(idx))
+	}
+
+	return strings.Join(output, ", ")
+}
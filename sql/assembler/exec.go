@@ -0,0 +1,270 @@
+package assembler
+
+import (
+	"context"
+	"iter"
+	"reflect"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+// ExecResult represents the aggregate outcome of running every batch of an assembler
+type ExecResult struct {
+	Batches      int
+	RowsAffected int64
+	Succeeded    []DataRange
+	Failed       []BatchError
+	RowErrors    []RowError
+	Retries      int
+	// HookErrors are the batches whose `After*` hooks failed once the statement went through. Their rows are written
+	// and count as succeeded, see `hooks.go`
+	HookErrors []BatchError
+}
+
+// DataRange represents the `DataStart..DataEnd` range of a batch. When the rows were sorted, `DataIndices` holds where
+// each of them sits in the data given. See `Indices()`.
+type DataRange struct {
+	Start       int
+	End         int
+	DataIndices []int
+}
+
+// Indices returns where the rows of the range sit in the data given, in the order they were sent
+func (dataRange DataRange) Indices() []int {
+	return getDataIndices(dataRange.Start, dataRange.End, dataRange.DataIndices)
+}
+
+// Exec runs every batch against the executor without reading back the `RETURNING` rows. Failing batches are never
+//      retried, see `retry.go`. The rows of the failing batch are sent to `DeadLetters`, if set.
+func (op BulkInsert) Exec(ctx context.Context, exec boil.ContextExecutor) (ExecResult, error) {
+	result, err := execBatches(ctx, exec, op.Batches(), false)
+	return sendDeadLetters(ctx, op.DeadLetters, result, err)
+}
+
+// ExecAndBind runs every batch against the executor and writes the `RETURNING` rows back into the data, so generated
+//             IDs and column defaults end up in the original structs. Every column the struct has a tag for is
+//             returned, see `ReturningAll`. Failing batches are never retried, see `retry.go`. The rows of the
+//             failing batch are sent to `DeadLetters`, if set.
+func (op BulkInsert) ExecAndBind(ctx context.Context, exec boil.ContextExecutor) (ExecResult, error) {
+	op.ReturningAll = true
+	result, err := execBatches(ctx, exec, op.Batches(), true)
+	return sendDeadLetters(ctx, op.DeadLetters, result, err)
+}
+
+// Exec runs every batch against the executor without reading back the `RETURNING` rows. Overridden for the same
+//      reasons as `Queries()`
+func (op BulkUpsert) Exec(ctx context.Context, exec boil.ContextExecutor) (ExecResult, error) {
+	result, err := execBatches(ctx, exec, op.Batches(), false)
+	return sendDeadLetters(ctx, op.DeadLetters, result, err)
+}
+
+// ExecAndBind runs every batch against the executor and writes the `RETURNING` rows back into the data. Overridden for
+//             the same reasons as `Queries()`. Turned down with `ErrBindDoNothing` when there is nothing to update, see
+//             `checkBind()`.
+func (op BulkUpsert) ExecAndBind(ctx context.Context, exec boil.ContextExecutor) (ExecResult, error) {
+	if err := op.checkBind(true); err != nil {
+		return ExecResult{}, err
+	}
+
+	op.ReturningAll = true
+	result, err := execBatches(ctx, exec, op.Batches(), true)
+	return sendDeadLetters(ctx, op.DeadLetters, result, err)
+}
+
+// execBatches runs the batches one after the other and stops at the first failure. The result still counts whatever
+//             went through before that. The batches after the failing one are never attempted, and so are not reported
+//             as failed.
+func execBatches(
+	ctx context.Context,
+	exec boil.ContextExecutor,
+	batches iter.Seq2[QueryGroup, error],
+	bind bool,
+) (ExecResult, error) {
+	result := ExecResult{}
+	for group, err := range batches {
+		if err != nil {
+			return result, err
+		}
+
+		result.Batches++
+		outcome, err := execGroup(ctx, exec, group, bind)
+		if err != nil {
+			return result, result.fail(group, err)
+		}
+
+		result.succeed(group, outcome)
+	}
+
+	return result, result.err()
+}
+
+// groupOutcome represents a batch, or a part of it, whose statement went through
+type groupOutcome struct {
+	affected int64
+	// hookErr is why the `After*` hooks failed afterwards, see `hooks.go`
+	hookErr error
+}
+
+// succeed records a batch, or a part of it, that went through
+func (result *ExecResult) succeed(group QueryGroup, outcome groupOutcome) {
+	result.RowsAffected += outcome.affected
+	result.Succeeded = append(result.Succeeded, DataRange{
+		Start:       group.DataStart,
+		End:         group.DataEnd,
+		DataIndices: group.DataIndices,
+	})
+
+	if outcome.hookErr != nil {
+		result.HookErrors = append(result.HookErrors, newBatchError(group, 1, outcome.hookErr))
+	}
+}
+
+// fail records a batch that did not go through and returns the error describing it
+func (result *ExecResult) fail(group QueryGroup, err error) error {
+	return result.failAfter(group, 1, err)
+}
+
+// failAfter records a batch that did not go through after a number of attempts
+func (result *ExecResult) failAfter(group QueryGroup, attempts int, err error) error {
+	batchErr := newBatchError(group, attempts, err)
+	result.Failed = append(result.Failed, batchErr)
+
+	return batchErr
+}
+
+// newBatchError describes what went wrong with a batch after a number of attempts
+func newBatchError(group QueryGroup, attempts int, err error) BatchError {
+	return BatchError{
+		Batch:       group.Batch,
+		DataStart:   group.DataStart,
+		DataEnd:     group.DataEnd,
+		DataIndices: group.DataIndices,
+		Attempts:    attempts,
+		Err:         err,
+		group:       group,
+	}
+}
+
+// merge adds the outcome of another execution to this one, which took a number of attempts to get to
+func (result *ExecResult) merge(other ExecResult, attempts int) {
+	for idx := range other.Failed {
+		other.Failed[idx].Attempts = attempts
+	}
+
+	result.Retries += other.Retries + attempts - 1
+	result.RowsAffected += other.RowsAffected
+	result.Succeeded = append(result.Succeeded, other.Succeeded...)
+	result.Failed = append(result.Failed, other.Failed...)
+	result.RowErrors = append(result.RowErrors, other.RowErrors...)
+	result.HookErrors = append(result.HookErrors, other.HookErrors...)
+}
+
+// err summarizes the failed batches and rows, if there are any, and then the hooks that failed after the statements
+func (result ExecResult) err() error {
+	switch {
+	case len(result.Failed) > 0:
+		return pkgerrors.Wrapf(ErrBatchFailed, "%d of %d batches", len(result.Failed), result.Batches)
+	case len(result.RowErrors) > 0:
+		return pkgerrors.Wrapf(ErrBatchFailed, "%d rows", len(result.RowErrors))
+	case len(result.HookErrors) > 0:
+		return pkgerrors.Wrapf(ErrAfterHookFailed, "%d of %d batches", len(result.HookErrors), result.Batches)
+	default:
+		return nil
+	}
+}
+
+// execGroup runs a single batch along with its hooks. When binding, the number of rows returned is reported as the rows
+//           affected. Constraint violations are reported as `ConstraintError`. The `After*` hooks failing does not fail
+//           the batch since the statement already went through, see `hooks.go`.
+func execGroup(ctx context.Context, exec boil.ContextExecutor, group QueryGroup, bind bool) (groupOutcome, error) {
+	group, err := group.hooks.before(ctx, exec, group)
+	if err != nil {
+		return groupOutcome{}, err
+	}
+
+	affected, err := execStatement(ctx, exec, group, bind)
+	if err != nil {
+		return groupOutcome{}, err
+	}
+
+	return groupOutcome{
+		affected: affected,
+		hookErr:  group.hooks.after(ctx, exec, group),
+	}, nil
+}
+
+// execStatement runs the statement of a single batch, see `execGroup()`
+func execStatement(ctx context.Context, exec boil.ContextExecutor, group QueryGroup, bind bool) (int64, error) {
+	if !bind {
+		output, err := group.Query.ExecContext(ctx, exec)
+		if err != nil {
+			return 0, enrichError(group, err)
+		}
+
+		return output.RowsAffected()
+	}
+
+	returned, err := bindGroup(ctx, exec, group)
+	if err != nil {
+		return 0, enrichError(group, err)
+	}
+
+	if err := writeBack(group.items, returned); err != nil {
+		return 0, err
+	}
+
+	return int64(returned.Len()), nil
+}
+
+// bindGroup runs a single batch and reads the `RETURNING` rows into new items. SQLBoiler cannot bind into maps, nor
+//           through other tags than `boil`, so those rows are scanned on our own.
+func bindGroup(ctx context.Context, exec boil.ContextExecutor, group QueryGroup) (reflect.Value, error) {
+	itemType := group.itemType()
+	if isMapType(itemType) || len(group.tagNames) > 0 {
+		rows, err := group.Statement.QueryContext(ctx, exec)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		defer rows.Close()
+
+		columns, err := rows.Columns()
+		if err != nil {
+			return reflect.Value{}, pkgerrors.WithStack(err)
+		}
+
+		return scanRows(rows, columns, itemType, group.tagNames)
+	}
+
+	returned := reflect.New(reflect.SliceOf(itemType))
+	if err := group.Query.Bind(ctx, exec, returned.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return returned.Elem(), nil
+}
+
+// writeBack copies the returned rows over the items they came from. Items that are neither pointers nor part of a
+//           slice cannot be written to, such as those received from a stream of struct values, and are left alone.
+//           The rows are matched to the items by position, so nothing is written when there are not as many of them,
+//           and `ErrBindMismatch` is returned instead. The statement went through by then.
+func writeBack(items []reflect.Value, returned reflect.Value) error {
+	count := returned.Len()
+	if count != len(items) {
+		return pkgerrors.Wrapf(ErrBindMismatch, "%d rows returned for %d items", count, len(items))
+	}
+
+	for idx := 0; idx < count; idx++ {
+		item := items[idx]
+		value := returned.Index(idx)
+
+		switch {
+		case item.Kind() == reflect.Ptr && !item.IsNil() && !value.IsNil():
+			item.Elem().Set(value.Elem())
+		case item.Kind() != reflect.Ptr && item.CanSet():
+			item.Set(value)
+		}
+	}
+
+	return nil
+}
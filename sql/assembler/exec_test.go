@@ -0,0 +1,191 @@
+package assembler
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"code.in.spdigital.sg/sp-digital/athena/db/pg"
+	"code.in.spdigital.sg/sp-digital/athena/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExec_writeBack(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	tcs := map[string]struct {
+		gvnPointers bool
+		gvnReturned int
+		expErr      error
+	}{
+		"success__pointers":         {gvnPointers: true, gvnReturned: 3},
+		"success__values":           {gvnPointers: false, gvnReturned: 3},
+		"failure__returned_partial": {gvnPointers: true, gvnReturned: 2, expErr: ErrBindMismatch},
+		"failure__returned_more":    {gvnPointers: false, gvnReturned: 4, expErr: ErrBindMismatch},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			values := []SampleTable{{Col01: "a"}, {Col01: "b"}, {Col01: "c"}}
+			pointers := []*SampleTable{{Col01: "a"}, {Col01: "b"}, {Col01: "c"}}
+
+			data := reflect.ValueOf(values)
+			returned := reflect.MakeSlice(reflect.TypeOf(values), 0, tc.gvnReturned)
+			if tc.gvnPointers {
+				data = reflect.ValueOf(pointers)
+				returned = reflect.MakeSlice(reflect.TypeOf(pointers), 0, tc.gvnReturned)
+			}
+
+			items := make([]reflect.Value, 0, data.Len())
+			for idx := 0; idx < data.Len(); idx++ {
+				items = append(items, data.Index(idx))
+			}
+
+			for idx := 0; idx < tc.gvnReturned; idx++ {
+				row := SampleTable{ID: int64(idx + 1), Col01: fmt.Sprintf("returned__%d", idx)}
+				if tc.gvnPointers {
+					returned = reflect.Append(returned, reflect.ValueOf(&row))
+				} else {
+					returned = reflect.Append(returned, reflect.ValueOf(row))
+				}
+			}
+
+			// When
+			err := writeBack(items, returned)
+
+			// Then
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			for idx := 0; idx < data.Len(); idx++ {
+				row := data.Index(idx)
+				if row.Kind() == reflect.Ptr {
+					row = row.Elem()
+				}
+
+				if tc.expErr == nil {
+					require.Equal(t, int64(idx+1), row.FieldByName("ID").Int())
+				} else {
+					require.Equal(t, int64(0), row.FieldByName("ID").Int())
+				}
+			}
+		})
+	}
+}
+
+func TestBulkInsert_ExecAndBind(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+		Col02 string `boil:"col_02"`
+	}
+	tableName := "sample"
+	createSQL := "" +
+		"CREATE TABLE \"" + tableName + "\" (\n" +
+		"    \"id\" BIGSERIAL PRIMARY KEY,\n" +
+		"    \"col_01\" TEXT,\n" +
+		"    \"col_02\" TEXT\n" +
+		");"
+
+	tcs := map[string]struct {
+		gvnRowsCount  int
+		gvnBatchSizes []int
+		expBatches    int
+	}{
+		"success__single_batch": {
+			gvnRowsCount: 100,
+			expBatches:   1,
+		},
+		"success__multiple_batches": {
+			gvnRowsCount:  100,
+			gvnBatchSizes: []int{32},
+			expBatches:    4,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			testutil.WithTxDB(t, func(tx pg.BeginnerExecutor) {
+				// Given
+				ctx := context.Background()
+
+				_, err := tx.ExecContext(ctx, createSQL)
+				require.NoError(t, err)
+
+				data := make([]*SampleTable, 0, tc.gvnRowsCount)
+				for idx := 0; idx < tc.gvnRowsCount; idx++ {
+					value := fmt.Sprintf("DataRow__%d", idx)
+					data = append(data, &SampleTable{Col01: value, Col02: value})
+				}
+
+				op, err := NewBulkInsert(data, tableName, []string{"col_01", "col_02"})
+				require.NoError(t, err)
+				op.BatchSizes = tc.gvnBatchSizes
+
+				// When
+				result, err := op.ExecAndBind(ctx, tx)
+
+				// Then
+				require.NoError(t, err)
+				require.Equal(t, tc.expBatches, result.Batches)
+				require.Equal(t, int64(tc.gvnRowsCount), result.RowsAffected)
+				for _, row := range data {
+					require.NotZero(t, row.ID)
+				}
+			})
+		})
+	}
+}
+
+func TestQueryGroup_itemType(t *testing.T) {
+	type SampleTable struct {
+		ID int64 `boil:"id"`
+	}
+
+	// Given
+	data := []interface{}{&SampleTable{ID: 1}, &SampleTable{ID: 2}}
+	op, err := NewBulkInsert(data, "sample", nil)
+	require.NoError(t, err)
+
+	// When
+	groups, err := op.Queries()
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, reflect.TypeFor[*SampleTable](), groups[0].itemType())
+}
+
+func TestBulkUpsert_ExecAndBind_DoNothing(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	// Given
+	data := []*SampleTable{{ID: 1, Col01: "a"}, {ID: 2, Col01: "b"}}
+	op, err := NewBulkUpsert(data, "sample_table", []string{"id"}, nil, []string{})
+	require.NoError(t, err)
+
+	exec := &fakeExecutor{}
+
+	// When
+	_, bindErr := op.ExecAndBind(context.Background(), exec)
+	_, parallelErr := op.ExecParallel(context.Background(), SharedConns(exec), ParallelOptions{Bind: true})
+	_, txErr := op.ExecTx(context.Background(), nil, ExecOptions{Bind: true})
+	_, execErr := op.Exec(context.Background(), exec)
+
+	// Then
+	require.ErrorIs(t, bindErr, ErrBindDoNothing)
+	require.ErrorIs(t, parallelErr, ErrBindDoNothing)
+	require.ErrorIs(t, txErr, ErrBindDoNothing)
+	require.NoError(t, execErr)
+	require.Equal(t, []*SampleTable{{ID: 1, Col01: "a"}, {ID: 2, Col01: "b"}}, data)
+}
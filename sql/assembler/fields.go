@@ -0,0 +1,292 @@
+package assembler
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+/**
+ * Question: which fields are looked into?
+ * Answer: the same ones SQLBoiler binds to, so the structs work the same way in both places:
+ *             > fields with a `boil` tag, as long as they are exported -- or any other tag, see `TagNames()`
+ *             > anonymous structs, or pointers to them, without a tag -- e.g. an embedded `Timestamps` or `*Audit`
+ *             > structs tagged `boil:",bind"`, embedded or not
+ *
+ *         Same as Go's own promoted fields, a column at a shallower level hides the same column further down. Two
+ *         fields with the same column at the same level are a conflict: neither is used, and the constructors report
+ *         it through `ColumnError`.
+ *
+ * Reading through a nil embedded pointer gives NULL. Writing through one allocates the struct first.
+ *
+ * Question: what do the tag options do?
+ * Answer: they mostly change which columns are inferred, see `columns.go`. Naming the columns explicitly still works,
+ *         except for the ones that can never be written:
+ *             > `omitempty` is inserted as `DEFAULT` when zero, for the database default to fill in, and so is never
+ *               inferred for the update. See `BulkInsert.DefaultWhenZero`, which it adds to.
+ *             > `readonly` is never inserted nor updated, e.g. generated columns. Naming it is a `ColumnError`.
+ *             > `pk` is inserted but never updated
+ *
+ *         `readonly` columns are returned by `RETURNING` along with the inserted ones. With `ReturningAll`, every
+ *         column is returned and scanned back, whatever its options.
+ *
+ * Question: isn't walking through the struct every time slow?
+ * Answer: it would be, which is why each type is only walked once per set of tag names and kept in `structMappings`
+ *         for good. The tag names come with every lookup rather than from a global setting, so that operations reading
+ *         different tags never step on each other. The values are then read through the index paths rather than
+ *         looking the fields up by name, which is a linear search per cell. The cached mappings are shared, so nothing
+ *         is supposed to modify them.
+ */
+
+// structMappings caches the mapping of every struct type met so far, keyed by `mappingKey`
+var structMappings sync.Map
+
+// defaultTagNames are the struct tags the columns are read from unless told otherwise, see `TagNames()`
+var defaultTagNames = []string{"boil"}
+
+// mappingKey identifies a cached mapping. The same type read through other tags is another mapping altogether.
+type mappingKey struct {
+	objType  reflect.Type
+	tagNames string
+}
+
+// getTag reads the first of the struct tags the field has, `boil` when none are given
+func getTag(field reflect.StructField, tagNames []string) string {
+	if len(tagNames) == 0 {
+		tagNames = defaultTagNames
+	}
+
+	for _, name := range tagNames {
+		if tag, found := field.Tag.Lookup(name); found {
+			return tag
+		}
+	}
+
+	return ""
+}
+
+// structField represents a struct field holding a column, wherever it sits among the embedded structs
+type structField struct {
+	// Name is the path of field names leading to the field, e.g. `Audit.CreatedBy`
+	Name   string
+	Column string
+	// Index is the path for `reflect.Value.FieldByIndex()`
+	Index []int
+
+	OmitEmpty  bool
+	ReadOnly   bool
+	PrimaryKey bool
+}
+
+// structMapping represents every column of a struct and the field holding it
+type structMapping struct {
+	// Fields are in the order they are declared, with the fields of embedded structs where they are embedded
+	Fields []structField
+	// Conflicts lists the fields claiming the same column at the same level
+	Conflicts map[string][]string
+
+	columns map[string]int
+}
+
+// field looks up the field holding the column
+func (mapping structMapping) field(column string) (structField, bool) {
+	idx, found := mapping.columns[column]
+	if !found {
+		return structField{}, false
+	}
+
+	return mapping.Fields[idx], true
+}
+
+// filter lists the columns whose fields are kept by `fn`, in the order they are declared
+func (mapping structMapping) filter(fn func(field structField) bool) []string {
+	columns := make([]string, 0, len(mapping.Fields))
+	for _, field := range mapping.Fields {
+		if fn(field) {
+			columns = append(columns, field.Column)
+		}
+	}
+
+	return columns
+}
+
+// getStructMapping returns the fields holding the columns of the struct read through `tagNames`, walking through it
+//                  only the first time it is met. Pointers to structs are looked through, anything else has no columns.
+func getStructMapping(objType reflect.Type, tagNames []string) structMapping {
+	if objType.Kind() == reflect.Ptr {
+		objType = objType.Elem()
+	}
+	if objType.Kind() != reflect.Struct {
+		return structMapping{}
+	}
+	if len(tagNames) == 0 {
+		tagNames = defaultTagNames
+	}
+
+	key := mappingKey{objType: objType, tagNames: strings.Join(tagNames, ",")}
+	if mapping, found := structMappings.Load(key); found {
+		return mapping.(structMapping)
+	}
+
+	// racing goroutines build the same mapping, so it does not matter whose is kept
+	mapping, _ := structMappings.LoadOrStore(key, buildStructMapping(objType, tagNames))
+	return mapping.(structMapping)
+}
+
+// buildStructMapping walks through the struct and its embedded structs for the fields holding the columns
+func buildStructMapping(objType reflect.Type, tagNames []string) structMapping {
+	type candidate struct {
+		field structField
+		depth int
+	}
+
+	order := make([]string, 0, objType.NumField())
+	candidates := make(map[string][]candidate, objType.NumField())
+
+	var walk func(objType reflect.Type, index []int, names []string, visited []reflect.Type)
+	walk = func(objType reflect.Type, index []int, names []string, visited []reflect.Type) {
+		for idx := 0; idx < objType.NumField(); idx++ {
+			field := objType.Field(idx)
+			column, options := parseTag(getTag(field, tagNames))
+			if column == "-" {
+				continue
+			}
+
+			fieldIndex := append(slices.Clone(index), idx)
+			fieldNames := append(slices.Clone(names), field.Name)
+
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+
+			embedded := (field.Anonymous && column == "") || slices.Contains(options, "bind")
+			if embedded && fieldType.Kind() == reflect.Struct {
+				// same as `encoding/json`, there is no way to allocate these from outside the package
+				if !field.IsExported() && field.Type.Kind() == reflect.Ptr {
+					continue
+				}
+
+				// guard against structs embedding themselves through pointers
+				if !slices.Contains(visited, fieldType) {
+					walk(fieldType, fieldIndex, fieldNames, append(visited, fieldType))
+				}
+				continue
+			}
+
+			if column == "" || !field.IsExported() {
+				continue
+			}
+
+			if _, found := candidates[column]; !found {
+				order = append(order, column)
+			}
+			candidates[column] = append(candidates[column], candidate{
+				field: structField{
+					Name:       strings.Join(fieldNames, "."),
+					Column:     column,
+					Index:      fieldIndex,
+					OmitEmpty:  slices.Contains(options, "omitempty"),
+					ReadOnly:   slices.Contains(options, "readonly"),
+					PrimaryKey: slices.Contains(options, "pk"),
+				},
+				depth: len(fieldIndex),
+			})
+		}
+	}
+	walk(objType, nil, nil, []reflect.Type{objType})
+
+	mapping := structMapping{
+		Fields:    make([]structField, 0, len(order)),
+		Conflicts: make(map[string][]string),
+		columns:   make(map[string]int, len(order)),
+	}
+	for _, column := range order {
+		shallowest := slices.MinFunc(candidates[column], func(left candidate, right candidate) int {
+			return left.depth - right.depth
+		})
+
+		names := make([]string, 0, 1)
+		for _, candidate := range candidates[column] {
+			if candidate.depth == shallowest.depth {
+				names = append(names, candidate.field.Name)
+			}
+		}
+
+		if len(names) > 1 {
+			mapping.Conflicts[column] = names
+			continue
+		}
+
+		mapping.columns[column] = len(mapping.Fields)
+		mapping.Fields = append(mapping.Fields, shallowest.field)
+	}
+
+	return mapping
+}
+
+// parseTag splits the tag into the column and the options after it, e.g. `boil:"id,pk,readonly"`
+func parseTag(tag string) (string, []string) {
+	column, options, _ := strings.Cut(tag, ",")
+	if options == "" {
+		return column, nil
+	}
+
+	return column, strings.Split(options, ",")
+}
+
+// getFieldValue reads the field out of the struct. Returns nil when going through an embedded pointer that is nil, or
+//               when the map row has no such key.
+func getFieldValue(item reflect.Value, field structField) interface{} {
+	value, _ := getCellValue(item, field)
+	return value
+}
+
+// getCellValue reads the field out of the struct or the map row, reporting whether the map row has the key at all.
+//              Struct fields are always there.
+func getCellValue(item reflect.Value, field structField) (interface{}, bool) {
+	if item.Kind() == reflect.Map {
+		return getMapValue(item, field.Column)
+	}
+
+	value, err := item.FieldByIndexErr(field.Index)
+	if err != nil {
+		return nil, true
+	}
+
+	return value.Interface(), true
+}
+
+// isZeroValue checks if the value is the zero value of its type, or a `driver.Valuer` giving NULL. A valid
+//             `null.Int64` holding 0 is neither.
+func isZeroValue(value interface{}) bool {
+	reflected := reflect.ValueOf(value)
+	if !reflected.IsValid() || reflected.IsZero() {
+		return true
+	}
+
+	if valuer, ok := value.(driver.Valuer); ok {
+		output, err := valuer.Value()
+		return err == nil && output == nil
+	}
+
+	return false
+}
+
+// getFieldTarget returns a pointer for writing into the field of the addressable struct, allocating the embedded
+//                pointers that are nil on the way
+func getFieldTarget(item reflect.Value, field structField) interface{} {
+	for depth, idx := range field.Index {
+		if depth > 0 && item.Kind() == reflect.Ptr {
+			if item.IsNil() {
+				item.Set(reflect.New(item.Type().Elem()))
+			}
+			item = item.Elem()
+		}
+		item = item.Field(idx)
+	}
+
+	return item.Addr().Interface()
+}
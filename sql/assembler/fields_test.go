@@ -0,0 +1,355 @@
+package assembler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sampleTimestamps struct {
+	CreatedAt int64 `boil:"created_at"`
+	UpdatedAt int64 `boil:"updated_at"`
+}
+
+type sampleAudit struct {
+	CreatedBy string `boil:"created_by"`
+}
+
+// SampleOwner is exported since embedded pointers to unexported structs are skipped
+type SampleOwner struct {
+	Owner string `boil:"owner"`
+}
+
+type sampleEmbedded struct {
+	ID    int64  `boil:"id"`
+	Col01 string `boil:"col_01"`
+	sampleTimestamps
+	*SampleOwner
+	Details struct {
+		Col02 string `boil:"col_02"`
+	} `boil:",bind"`
+}
+
+func TestGetStructMapping(t *testing.T) {
+	type SampleShadowed struct {
+		CreatedAt int64 `boil:"created_at"`
+		sampleTimestamps
+	}
+
+	type SampleConflict struct {
+		ID int64 `boil:"id"`
+		sampleAudit
+		Other struct {
+			CreatedBy string `boil:"created_by"`
+		} `boil:",bind"`
+	}
+
+	type SampleRecursive struct {
+		ID int64 `boil:"id"`
+		*SampleRecursive
+	}
+
+	tcs := map[string]struct {
+		gvnType      reflect.Type
+		expColumns   []string
+		expNames     []string
+		expConflicts map[string][]string
+	}{
+		"success__embedded": {
+			gvnType:      reflect.TypeFor[sampleEmbedded](),
+			expColumns:   []string{"id", "col_01", "created_at", "updated_at", "owner", "col_02"},
+			expNames:     []string{"ID", "Col01", "sampleTimestamps.CreatedAt", "sampleTimestamps.UpdatedAt", "SampleOwner.Owner", "Details.Col02"},
+			expConflicts: map[string][]string{},
+		},
+		"success__shadowed": {
+			gvnType:      reflect.TypeFor[SampleShadowed](),
+			expColumns:   []string{"created_at", "updated_at"},
+			expNames:     []string{"CreatedAt", "sampleTimestamps.UpdatedAt"},
+			expConflicts: map[string][]string{},
+		},
+		"success__recursive": {
+			gvnType:      reflect.TypeFor[*SampleRecursive](),
+			expColumns:   []string{"id"},
+			expNames:     []string{"ID"},
+			expConflicts: map[string][]string{},
+		},
+		"failure__conflict": {
+			gvnType:      reflect.TypeFor[SampleConflict](),
+			expColumns:   []string{"id"},
+			expNames:     []string{"ID"},
+			expConflicts: map[string][]string{"created_by": {"sampleAudit.CreatedBy", "Other.CreatedBy"}},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			columns := make([]string, 0, len(tc.expColumns))
+			names := make([]string, 0, len(tc.expNames))
+
+			// When
+			mapping := getStructMapping(tc.gvnType, nil)
+			for _, field := range mapping.Fields {
+				columns = append(columns, field.Column)
+				names = append(names, field.Name)
+			}
+
+			// Then
+			require.Equal(t, tc.expColumns, columns)
+			require.Equal(t, tc.expNames, names)
+			require.Equal(t, tc.expConflicts, mapping.Conflicts)
+		})
+	}
+}
+
+func TestBulkInsert_EmbeddedArgs(t *testing.T) {
+	// Given
+	item := sampleEmbedded{ID: 1, Col01: "one"}
+	item.CreatedAt = 10
+	item.UpdatedAt = 20
+	item.Details.Col02 = "two"
+
+	op, err := NewBulkInsert([]sampleEmbedded{item}, "sample_table", []string{"id", "created_at", "owner", "col_02"})
+	require.NoError(t, err)
+
+	// When
+	groups, err := op.Queries()
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Equal(t, []interface{}{int64(1), int64(10), nil, "two"}, groups[0].Args)
+}
+
+func TestGetFieldValue_NilEmbed(t *testing.T) {
+	type SampleTable struct {
+		ID int64 `boil:"id"`
+		*SampleOwner
+	}
+
+	// Given
+	field, found := getStructMapping(reflect.TypeFor[SampleTable](), nil).field("owner")
+	require.True(t, found)
+
+	// When
+	value := getFieldValue(reflect.ValueOf(SampleTable{ID: 1}), field)
+
+	// Then
+	require.Nil(t, value)
+}
+
+func TestGetFieldTarget_NilEmbed(t *testing.T) {
+	type SampleTable struct {
+		ID int64 `boil:"id"`
+		*SampleOwner
+	}
+
+	// Given
+	item := SampleTable{ID: 1}
+	field, found := getStructMapping(reflect.TypeFor[SampleTable](), nil).field("owner")
+	require.True(t, found)
+
+	// When
+	target := getFieldTarget(reflect.ValueOf(&item).Elem(), field)
+	*target.(*string) = "someone"
+
+	// Then
+	require.NotNil(t, item.SampleOwner)
+	require.Equal(t, "someone", item.Owner)
+}
+
+func TestValidateColumns_Conflict(t *testing.T) {
+	type SampleTable struct {
+		ID int64 `boil:"id"`
+		sampleAudit
+		Other struct {
+			CreatedBy string `boil:"created_by"`
+		} `boil:",bind"`
+	}
+
+	// Given
+	data := []SampleTable{{ID: 1}}
+
+	// When
+	_, err := NewBulkInsert(data, "sample_table", []string{"id", "created_by"})
+
+	// Then
+	var columnErr ColumnError
+	require.True(t, errors.As(err, &columnErr))
+	require.ErrorIs(t, err, ErrColumnInvalid)
+	require.Equal(t, map[string][]string{"created_by": {"sampleAudit.CreatedBy", "Other.CreatedBy"}}, columnErr.Conflicts)
+	require.Empty(t, columnErr.Unknown)
+	require.Contains(t, err.Error(), `conflicting columns "created_by" (sampleAudit.CreatedBy, Other.CreatedBy)`)
+}
+
+func TestTagNames(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `db:"id"`
+		Col01 string `boil:"col_01" db:"other_01"`
+		Col02 string `db:"-"`
+	}
+
+	tcs := map[string]struct {
+		gvnNames   []string
+		expColumns []string
+	}{
+		"success__default": {
+			gvnNames:   []string{"boil"},
+			expColumns: []string{"col_01"},
+		},
+		"success__db": {
+			gvnNames:   []string{"db"},
+			expColumns: []string{"id", "other_01"},
+		},
+		"success__preference": {
+			gvnNames:   []string{"boil", "db"},
+			expColumns: []string{"id", "col_01"},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []SampleTable{{ID: 1, Col01: "one"}}
+
+			// When
+			op, err := NewBulkInsert(data, "sample_table", nil, TagNames(tc.gvnNames...))
+
+			// Then
+			require.NoError(t, err)
+			require.Equal(t, tc.expColumns, op.Columns)
+
+			groups, err := op.Queries()
+			require.NoError(t, err)
+			require.Len(t, groups[0].Args, len(tc.expColumns))
+		})
+	}
+}
+
+func TestGetStructMapping_Options(t *testing.T) {
+	type SampleTable struct {
+		ID        int64  `boil:"id,pk,readonly"`
+		Code      string `boil:"code,pk"`
+		Col01     string `boil:"col_01,omitempty"`
+		Col02     string `boil:"col_02"`
+		Generated string `boil:"generated,readonly"`
+	}
+
+	// Given
+	itemType := reflect.TypeFor[SampleTable]()
+
+	// When
+	mapping := getStructMapping(itemType, nil)
+
+	// Then
+	require.Equal(t, []string{"id", "code", "col_01", "col_02", "generated"}, getStructColumns(itemType, nil))
+	require.Equal(t, []string{"id", "code"}, mapping.filter(func(field structField) bool { return field.PrimaryKey }))
+	require.Equal(t, []string{"id", "generated"}, mapping.filter(func(field structField) bool { return field.ReadOnly }))
+	require.Equal(t, []string{"col_01"}, mapping.filter(func(field structField) bool { return field.OmitEmpty }))
+}
+
+func TestGetStructMapping_Cache(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id" db:"db_id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	// Given
+	itemType := reflect.TypeFor[SampleTable]()
+
+	// When
+	mappings := make(chan structMapping, 8)
+	for range cap(mappings) {
+		go func() { mappings <- getStructMapping(itemType, nil) }()
+	}
+
+	// Then
+	first := <-mappings
+	for range cap(mappings) - 1 {
+		mapping := <-mappings
+		require.Equal(t, first.Fields, mapping.Fields)
+	}
+
+	cached, found := structMappings.Load(mappingKey{objType: itemType, tagNames: "boil"})
+	require.True(t, found)
+	require.Equal(t, first.Fields, cached.(structMapping).Fields)
+
+	require.Equal(t, []string{"db_id"}, getStructColumns(itemType, []string{"db"}))
+}
+
+type sampleBenchmark struct {
+	ID    int64  `boil:"id"`
+	Col01 string `boil:"col_01"`
+	Col02 string `boil:"col_02"`
+	Col03 int64  `boil:"col_03"`
+	sampleTimestamps
+	Col04 string `boil:"col_04"`
+	Col05 int64  `boil:"col_05"`
+}
+
+func BenchmarkGetStructMapping(b *testing.B) {
+	itemType := reflect.TypeFor[sampleBenchmark]()
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			getStructMapping(itemType, nil)
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			buildStructMapping(itemType, defaultTagNames)
+		}
+	})
+}
+
+func BenchmarkGetFieldValue(b *testing.B) {
+	item := reflect.ValueOf(sampleBenchmark{ID: 1, Col05: 5})
+	fields := getStructMapping(item.Type(), nil).Fields
+
+	b.Run("by_index", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			for _, field := range fields {
+				getFieldValue(item, field)
+			}
+		}
+	})
+
+	b.Run("by_name", func(b *testing.B) {
+		names := make([]string, 0, len(fields))
+		for _, field := range fields {
+			names = append(names, field.Name[strings.LastIndex(field.Name, ".")+1:])
+		}
+
+		b.ReportAllocs()
+		for range b.N {
+			for _, name := range names {
+				item.FieldByName(name).Interface()
+			}
+		}
+	})
+}
+
+func BenchmarkBulkInsert_Queries(b *testing.B) {
+	data := make([]sampleBenchmark, 10_000)
+	for idx := range data {
+		data[idx] = sampleBenchmark{ID: int64(idx), Col01: "one", Col05: 5}
+	}
+
+	op, err := NewBulkInsert(data, "sample_table", nil)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := op.Queries(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package assembler
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+/**
+ * Question: why not call SQLBoiler's own hooks?
+ * Answer: the generated `doBeforeInsertHooks()` and the like are unexported, so there is no way to call them from
+ *         here. Models opt in by implementing `BeforeInserter` and the rest instead, which is usually a one-liner
+ *         calling the generated hooks from within the `orm` package.
+ *
+ * Question: when do the hooks run?
+ * Answer: around every statement, with the executor it runs on -- the transaction, when there is one:
+ *             > the `Before*` methods of the rows, then `BeforeBatch`. The arguments are extracted again afterwards, so
+ *               whatever the hooks changed in the rows is what gets sent.
+ *             > the statement itself, binding the `RETURNING` rows back when asked to
+ *             > the `After*` methods of the rows, then `AfterBatch`
+ *
+ *         A failing `Before*` hook fails the batch like a failing statement would, and is reported as `ErrHookFailed`.
+ *         Hooks run again with every attempt: retries, and each half of a bisected batch. The executors of
+ *         `pgxassembler` cannot run them at all, having no `boil.ContextExecutor` to give.
+ *
+ * Question: why does a failing `After*` hook not fail the batch?
+ * Answer: the statement already went through by then, and outside of a transaction it is already committed. Failing
+ *         the batch would report rows as not written while they are, then retry them or send them to the dead letters.
+ *         So the rows count as succeeded, and the failure goes into the result's `HookErrors` as `ErrAfterHookFailed`
+ *         instead, which is also what the execution returns when nothing else went wrong. The same goes for the
+ *         executors running their own transactions, which still commit the batch.
+ *
+ *         Hooks that have to take the rows back with them belong in a transaction of the caller's, rolled back on
+ *         `ErrAfterHookFailed`.
+ */
+
+// BeforeInserter is implemented by the rows that need to run something before they are inserted
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context, exec boil.ContextExecutor) error
+}
+
+// AfterInserter is implemented by the rows that need to run something after they are inserted
+type AfterInserter interface {
+	AfterInsert(ctx context.Context, exec boil.ContextExecutor) error
+}
+
+// BeforeUpserter is implemented by the rows that need to run something before they are upserted
+type BeforeUpserter interface {
+	BeforeUpsert(ctx context.Context, exec boil.ContextExecutor) error
+}
+
+// AfterUpserter is implemented by the rows that need to run something after they are upserted
+type AfterUpserter interface {
+	AfterUpsert(ctx context.Context, exec boil.ContextExecutor) error
+}
+
+// BatchHook receives the batch about to run or that just ran, see `QueryGroup.Items()` for its rows
+type BatchHook func(ctx context.Context, exec boil.ContextExecutor, group QueryGroup) error
+
+// Hooks run around the statement of every batch, see the top of the file
+type Hooks struct {
+	// Rows calls the hook methods of the rows themselves, e.g. `BeforeInserter`
+	Rows bool
+	// BeforeBatch runs before the statement, after the hook methods of the rows
+	BeforeBatch BatchHook
+	// AfterBatch runs after the statement went through, after the hook methods of the rows. Failing does not fail the
+	// batch, see the top of the file
+	AfterBatch BatchHook
+}
+
+// groupHooks are the hooks handed down to each batch, along with what is needed to run them
+type groupHooks struct {
+	Hooks
+	upsert bool
+	// rebuild extracts the arguments of the batch again, once the rows may have been changed
+	rebuild func(group QueryGroup) (QueryGroup, error)
+}
+
+// Items returns the rows of the batch as they were given, e.g. the pointers to the structs
+func (group QueryGroup) Items() []interface{} {
+	items := make([]interface{}, 0, len(group.items))
+	for _, item := range group.items {
+		items = append(items, item.Interface())
+	}
+
+	return items
+}
+
+// withHooks hands the hooks down to every batch
+func withHooks(batches iter.Seq2[QueryGroup, error], hooks *groupHooks) iter.Seq2[QueryGroup, error] {
+	if hooks == nil {
+		return batches
+	}
+
+	return func(yield func(QueryGroup, error) bool) {
+		for group, err := range batches {
+			group.hooks = hooks
+			if !yield(group, err) {
+				return
+			}
+		}
+	}
+}
+
+// groupHooks prepares the hooks of the operation for the batches, nil when there are none
+func (op BulkInsert) groupHooks(upsert bool, subgroup func(QueryGroup, int, int) (QueryGroup, error)) *groupHooks {
+	if op.Hooks == nil {
+		return nil
+	}
+
+	return &groupHooks{
+		Hooks:  *op.Hooks,
+		upsert: upsert,
+		rebuild: func(group QueryGroup) (QueryGroup, error) {
+			return subgroup(group, 0, len(group.items))
+		},
+	}
+}
+
+// before runs the hooks due before the statement, returning the batch rebuilt with whatever they changed
+func (hooks *groupHooks) before(ctx context.Context, exec boil.ContextExecutor, group QueryGroup) (QueryGroup, error) {
+	if hooks == nil || (!hooks.Rows && hooks.BeforeBatch == nil) {
+		return group, nil
+	}
+
+	if hooks.Rows {
+		err := hooks.eachRow(group, ErrHookFailed, func(row interface{}) error {
+			switch {
+			case hooks.upsert:
+				if hook, ok := row.(BeforeUpserter); ok {
+					return hook.BeforeUpsert(ctx, exec)
+				}
+			default:
+				if hook, ok := row.(BeforeInserter); ok {
+					return hook.BeforeInsert(ctx, exec)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return group, err
+		}
+	}
+
+	if hooks.BeforeBatch != nil {
+		if err := hooks.BeforeBatch(ctx, exec, group); err != nil {
+			return group, fmt.Errorf("%w: before batch %d: %w", ErrHookFailed, group.Batch, err)
+		}
+	}
+
+	rebuilt, err := hooks.rebuild(group)
+	if err != nil {
+		return group, err
+	}
+	rebuilt.hooks = hooks
+
+	return rebuilt, nil
+}
+
+// after runs the hooks due after the statement went through. Failures are reported as `ErrAfterHookFailed`.
+func (hooks *groupHooks) after(ctx context.Context, exec boil.ContextExecutor, group QueryGroup) error {
+	if hooks == nil {
+		return nil
+	}
+
+	if hooks.Rows {
+		err := hooks.eachRow(group, ErrAfterHookFailed, func(row interface{}) error {
+			switch {
+			case hooks.upsert:
+				if hook, ok := row.(AfterUpserter); ok {
+					return hook.AfterUpsert(ctx, exec)
+				}
+			default:
+				if hook, ok := row.(AfterInserter); ok {
+					return hook.AfterInsert(ctx, exec)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if hooks.AfterBatch != nil {
+		if err := hooks.AfterBatch(ctx, exec, group); err != nil {
+			return fmt.Errorf("%w: after batch %d: %w", ErrAfterHookFailed, group.Batch, err)
+		}
+	}
+
+	return nil
+}
+
+// eachRow calls `fn` with every row of the batch, through a pointer whenever possible since the generated hooks have
+//         pointer receivers. Failures are reported as `hookErr`.
+func (hooks *groupHooks) eachRow(group QueryGroup, hookErr error, fn func(row interface{}) error) error {
+	for idx, item := range group.items {
+		row := item.Interface()
+		if item.Kind() != reflect.Ptr && item.CanAddr() {
+			row = item.Addr().Interface()
+		}
+
+		if err := fn(row); err != nil {
+			return fmt.Errorf("%w: data %d: %w", hookErr, group.DataIndex(idx), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package assembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+// hookedRow records its hooks into `calls`, failing those named in `fail`
+type hookedRow struct {
+	ID    int64  `boil:"id"`
+	Col01 string `boil:"col_01"`
+
+	calls *hookCalls
+	fail  string
+}
+
+// hookCalls keeps the hooks in the order they were called
+type hookCalls struct {
+	mutex sync.Mutex
+	names []string
+}
+
+func (calls *hookCalls) add(name string) {
+	calls.mutex.Lock()
+	defer calls.mutex.Unlock()
+
+	calls.names = append(calls.names, name)
+}
+
+func (row *hookedRow) call(name string) error {
+	row.calls.add(fmt.Sprintf("%s %d", name, row.ID))
+	if row.fail == name {
+		return errors.New("bad row")
+	}
+
+	return nil
+}
+
+func (row *hookedRow) BeforeInsert(ctx context.Context, exec boil.ContextExecutor) error {
+	row.Col01 = "inserted"
+	return row.call("BeforeInsert")
+}
+
+func (row *hookedRow) AfterInsert(ctx context.Context, exec boil.ContextExecutor) error {
+	return row.call("AfterInsert")
+}
+
+func (row *hookedRow) BeforeUpsert(ctx context.Context, exec boil.ContextExecutor) error {
+	row.Col01 = "upserted"
+	return row.call("BeforeUpsert")
+}
+
+func (row *hookedRow) AfterUpsert(ctx context.Context, exec boil.ContextExecutor) error {
+	return row.call("AfterUpsert")
+}
+
+func TestHooks(t *testing.T) {
+	tcs := map[string]struct {
+		gvnUpsert bool
+		gvnRows   bool
+		gvnFail   string
+		expCalls  []string
+		expArgs   []interface{}
+		expErr    error
+		// expHookErr is expected from the `After*` hooks, which leave the rows written
+		expHookErr error
+	}{
+		"success__insert": {
+			gvnRows: true,
+			expCalls: []string{
+				"BeforeInsert 1", "BeforeInsert 2", "BeforeBatch",
+				"AfterInsert 1", "AfterInsert 2", "AfterBatch",
+			},
+			expArgs: []interface{}{int64(1), "inserted", int64(2), "inserted"},
+		},
+		"success__upsert": {
+			gvnUpsert: true,
+			gvnRows:   true,
+			expCalls: []string{
+				"BeforeUpsert 1", "BeforeUpsert 2", "BeforeBatch",
+				"AfterUpsert 1", "AfterUpsert 2", "AfterBatch",
+			},
+			expArgs: []interface{}{int64(1), "upserted", int64(2), "upserted"},
+		},
+		"success__batch_only": {
+			gvnRows:  false,
+			expCalls: []string{"BeforeBatch", "AfterBatch"},
+			expArgs:  []interface{}{int64(1), "", int64(2), ""},
+		},
+		"failure__before": {
+			gvnRows:  true,
+			gvnFail:  "BeforeInsert",
+			expCalls: []string{"BeforeInsert 1", "BeforeInsert 2"},
+			expErr:   ErrHookFailed,
+		},
+		"failure__after": {
+			gvnRows: true,
+			gvnFail: "AfterInsert",
+			expCalls: []string{
+				"BeforeInsert 1", "BeforeInsert 2", "BeforeBatch",
+				"AfterInsert 1", "AfterInsert 2",
+			},
+			expArgs:    []interface{}{int64(1), "inserted", int64(2), "inserted"},
+			expHookErr: ErrAfterHookFailed,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			calls := &hookCalls{}
+			data := []hookedRow{
+				{ID: 1, calls: calls},
+				{ID: 2, calls: calls, fail: tc.gvnFail},
+			}
+
+			var sent []interface{}
+			exec := &fakeExecutor{fail: func(args []interface{}) error {
+				sent = args
+				return nil
+			}}
+
+			hooks := &Hooks{
+				Rows: tc.gvnRows,
+				BeforeBatch: func(ctx context.Context, exec boil.ContextExecutor, group QueryGroup) error {
+					require.Len(t, group.Items(), 2)
+					calls.add("BeforeBatch")
+					return nil
+				},
+				AfterBatch: func(ctx context.Context, exec boil.ContextExecutor, group QueryGroup) error {
+					calls.add("AfterBatch")
+					return nil
+				},
+			}
+
+			var (
+				result ExecResult
+				err    error
+			)
+
+			// When
+			if tc.gvnUpsert {
+				op, opErr := NewBulkUpsert(data, "sample", []string{"id"}, nil, nil)
+				require.NoError(t, opErr)
+				op.Hooks = hooks
+				result, err = op.Exec(context.Background(), exec)
+			} else {
+				op, opErr := NewBulkInsert(data, "sample", nil)
+				require.NoError(t, opErr)
+				op.Hooks = hooks
+				result, err = op.Exec(context.Background(), exec)
+			}
+
+			// Then
+			require.Equal(t, tc.expCalls, calls.names)
+			require.Equal(t, tc.expArgs, sent)
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				require.ErrorContains(t, err, "data 1: bad row")
+				require.Len(t, result.Failed, 1)
+				return
+			}
+
+			require.Empty(t, result.Failed)
+			require.Equal(t, int64(2), result.RowsAffected)
+			if tc.expHookErr != nil {
+				require.ErrorIs(t, err, tc.expHookErr)
+				require.NotErrorIs(t, err, ErrHookFailed)
+				require.Len(t, result.HookErrors, 1)
+				require.ErrorContains(t, result.HookErrors[0], "data 1: bad row")
+				return
+			}
+
+			require.NoError(t, err)
+			require.Empty(t, result.HookErrors)
+		})
+	}
+}
@@ -1,12 +1,13 @@
 package assembler
 
 import (
-	"math"
+	"iter"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 
-	boilQueries "github.com/volatiletech/sqlboiler/v4/queries"
-	"github.com/volatiletech/strmangle"
+	pkgerrors "github.com/pkg/errors"
 )
 
 /**
@@ -27,9 +28,25 @@ import (
  *
  * therefore:
  *     - column specificiation policy: always whitelist
+ *         -- update: the columns can be inferred from the `boil` tags instead, see `columns.go`
  *     - no caching needed
+ *         -- update: the mapping of columns and struct fields is cached after all, see `fields.go`
  *
  * for `RETURNING` clause, I want to keep it simple -- if it's involved in the query, return it.
+ *     -- update: along with the `readonly` columns, which the database fills in by definition
+ *     -- update: `ExecAndBind()` returns every column the struct has a `boil` tag for instead, since it needs the
+ *        columns that the database fills in (serial IDs, defaults) which are precisely the ones left out of the INSERT.
+ *        It is opt-in through `ReturningAll` for everyone else, as structs may well have tagged fields that are not
+ *        in the table.
+ */
+
+/**
+ * Question: what does `DefaultWhenZero` cost?
+ * Answer: the `DEFAULT` cells are part of the SQL text, so the statement of a batch depends on which of its cells are
+ *         zero. Batches of the same size no longer share a statement nor a `Fingerprint` as soon as their zero cells
+ *         sit elsewhere, which defeats `BatchSizes` and any cache of prepared statements keyed by the fingerprint.
+ *         Stick to a fixed column list when that matters, e.g. by leaving the serial column out of `Columns` for the
+ *         rows that have no ID and inserting the others separately.
  */
 
 // BulkInsert represents an assembler for bulk insert SQL
@@ -39,32 +56,107 @@ type BulkInsert struct {
 	DataValue reflect.Value
 	Table     string
 	Columns   []string
+	// BatchSizes restricts the batches to these row counts, plus 1 for the tail, so that the resulting statements are
+	// reusable. See `BatchSizesPowersOfTwo()` and `getBatchSizes()`. Leave empty to pack as many rows as possible per
+	// batch.
+	BatchSizes []int
+	// SortBy sends the rows ordered by these columns so that concurrent statements lock them in the same order, e.g.
+	// the `ConflictTargets` of an upsert. Off unless set. `DataStart` and `DataEnd` then index into `Order()` rather
+	// than the data, see `DataIndices` for mapping the batches back to the data.
+	SortBy []string
+	// MissingKeys decides what is sent for the columns that map rows have no key for. NULL unless set.
+	MissingKeys MissingKey
+	// DefaultWhenZero sends `DEFAULT` instead of the value of these columns when it is zero or NULL, e.g. to let the
+	// database generate the serial IDs of the rows that have none yet. See `sqlGroup()` and `WithDefaultWhenZero()`,
+	// which upserts need.
+	DefaultWhenZero []string
+	// Timestamps sets the creation and update times of the rows when set. See `WithTimestamps()`, which also adds the
+	// columns when they are missing.
+	Timestamps *Timestamps
+	// Hooks run around the statement of every batch when set, see `hooks.go`
+	Hooks *Hooks
+	// DeadLetters receives the rows that could not be written by `Exec()` and `ExecAndBind()`, and by the other
+	// executors unless their options name a sink of their own. See `deadletter.go`
+	DeadLetters DeadLetterSink
+	// ReturningAll returns every column the struct has a tag for rather than only the inserted ones. Set by the
+	// executors that write the rows back, see `ExecAndBind()`.
+	ReturningAll bool
+	// TagNames are the struct tags the columns are read from, `boil` when empty. Given to the constructors through
+	// `TagNames()` since that is when the columns are inferred.
+	TagNames []string
+
+	// updated are the columns an upsert sets on conflict, which map rows cannot leave out with `MissingKeyDefault`
+	updated []string
 }
 
-// Fields returns the list of struct fields that are annotated as database ORM fields
+// WithDefaultWhenZero returns a copy that sends `DEFAULT` for these columns when they are zero or NULL, on top of the
+//                     ones already in `DefaultWhenZero`
+func (op BulkInsert) WithDefaultWhenZero(columns ...string) BulkInsert {
+	op.DefaultWhenZero = withColumns(op.DefaultWhenZero, columns)
+	return op
+}
+
+// defaultColumns lists the columns sent as `DEFAULT` when zero: `DefaultWhenZero` along with the fields tagged
+//                `omitempty`
+func (op BulkInsert) defaultColumns() []string {
+	return withColumns(op.DefaultWhenZero, getOmitEmptyColumns(op.itemType(), op.TagNames))
+}
+
+// Fields returns the list of struct fields that are annotated as database ORM fields. Fields of embedded structs are
+//        named by their path, e.g. `Timestamps.CreatedAt`.
 func (op BulkInsert) Fields() ([]string, error) {
+	fields, err := op.fields()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, field.Name)
+	}
+
+	return names, nil
+}
+
+// fields returns the struct fields of the columns, along with their index paths
+func (op BulkInsert) fields() ([]structField, error) {
 	return getStructFields(
-		op.DataValue.Index(0),
+		op.itemType(),
 		op.Columns,
+		op.TagNames,
 	)
 }
 
-// Queries returns the built SQL statement as a SQLBoiler `queries.Query` object
-func (op BulkInsert) Queries() ([]QueryGroup, error) {
-	groups, err := op.sqlData()
-	if err != nil {
-		return nil, err
+// itemType returns the type of each item of the data, see `getDataItemType()`
+func (op BulkInsert) itemType() reflect.Type {
+	return getDataItemType(op.DataType, op.DataValue)
+}
+
+// returningColumns lists the columns for the `RETURNING` clause: the inserted ones along with the `readonly` ones, or
+//                  everything the struct can hold with `ReturningAll`
+func (op BulkInsert) returningColumns() []string {
+	if !op.ReturningAll {
+		return withColumns(op.Columns, getReadOnlyColumns(op.itemType(), op.TagNames))
 	}
 
-	for idx := range groups {
-		group := &groups[idx]
-		group.Query = boilQueries.Raw(
-			op.sqlStatement(*group),
-			group.Args...,
-		)
+	columns := getStructColumns(op.itemType(), op.TagNames)
+	if len(columns) == 0 {
+		return op.Columns
 	}
 
-	return groups, nil
+	return columns
+}
+
+// Queries returns the built SQL statement as a SQLBoiler `queries.Query` object. All the batches are built up front,
+//         see `Batches()` for building them one at a time.
+func (op BulkInsert) Queries() ([]QueryGroup, error) {
+	return collectGroups(op.Batches())
+}
+
+// Batches returns an iterator that builds each batch only when it is asked for, so that only one batch's worth of SQL
+//         and arguments are held in memory at a time
+func (op BulkInsert) Batches() iter.Seq2[QueryGroup, error] {
+	return withHooks(withStatement(op.sqlData(), op.sqlStatement), op.groupHooks(false, op.subgroup))
 }
 
 // SQL builds the raw SQL that can be easily passed to SQLBoiler's APIs
@@ -74,59 +166,210 @@ func (op BulkInsert) sqlStatement(group QueryGroup) string {
 		"INSERT INTO \"" + op.Table + "\" (" + cols + ")\n" +
 		"VALUES\n" +
 		strings.Join(group.Rows, ",\n") + "\n" +
-		"RETURNING (" + cols + ")"
+		"RETURNING " + strings.Join(quoteNames(op.returningColumns()), ",")
 
 	return sql
 }
 
 // sqlData extracts values from the array of structs. For `orm.*` structs, there seem to be no pointers generated who
 //         instead represented with a `null.*` counterpart.
-func (op BulkInsert) sqlData() ([]QueryGroup, error) {
-	fields, err := op.Fields()
-	if err != nil {
-		return nil, err
+//
+//         Items are buffered until there are enough for the largest batch allowed, so that streams are never read more
+//         than a batch ahead. Whatever is left in the buffer at the end is split according to `BatchSizes`.
+//
+//         With `SortBy`, slices are sorted as a whole before being split while streams are sorted one buffer at a time.
+func (op BulkInsert) sqlData() iter.Seq2[QueryGroup, error] {
+	return func(yield func(QueryGroup, error) bool) {
+		fields, err := op.fields()
+		if err != nil {
+			yield(QueryGroup{}, err)
+			return
+		}
+
+		if missing := withoutColumns(op.DefaultWhenZero, op.Columns); len(missing) > 0 {
+			yield(QueryGroup{}, pkgerrors.Wrapf(ErrColumnInvalid, "DEFAULT when zero for columns not inserted %q", missing))
+			return
+		}
+
+		order, err := op.Order()
+		if err != nil {
+			yield(QueryGroup{}, err)
+			return
+		}
+
+		// streams cannot be sorted up front, so each batch is sorted on its own instead
+		var sortFields []structField
+		if len(op.SortBy) > 0 && order == nil {
+			if sortFields, err = op.sortFields(); err != nil {
+				yield(QueryGroup{}, err)
+				return
+			}
+		}
+
+		batchLen, _ := getBatchingInfo(psqlMaxParamCount, len(fields), psqlMaxParamCount)
+		sizes, err := getBatchSizes(batchLen, batchLen, op.BatchSizes)
+		if err != nil {
+			yield(QueryGroup{}, err)
+			return
+		}
+		bufferLen := sizes[0]
+
+		batch := 0
+		idxBase := 0
+		emit := func(items []reflect.Value, indices []int) bool {
+			if len(op.SortBy) == 0 {
+				indices = nil
+			}
+
+			group, err := op.sqlGroup(fields, idxBase, items, indices)
+			if err != nil {
+				yield(QueryGroup{}, err)
+				return false
+			}
+			group.Batch = batch
+
+			batch++
+			idxBase += len(items)
+			return yield(group, nil)
+		}
+
+		items := getItems(op.DataType, op.DataValue)
+		if order != nil {
+			items = getItemsInOrder(op.DataValue, order)
+		}
+
+		position := 0
+		buffer := make([]reflect.Value, 0, bufferLen)
+		indices := make([]int, 0, bufferLen)
+		for item := range items {
+			index := position
+			if order != nil {
+				index = order[position]
+			}
+			position++
+
+			// streams are only checked as they are read, see `validateItems()` for slices
+			if err := validateItem(item); err != nil {
+				yield(QueryGroup{}, pkgerrors.WithStack(ItemError{
+					DataIndex: index,
+					Type:      getItemTypeName(item),
+					Err:       err,
+				}))
+				return
+			}
+
+			buffer = append(buffer, item)
+			indices = append(indices, index)
+			if len(buffer) < bufferLen {
+				continue
+			}
+
+			if sortFields != nil {
+				sortItems(buffer, indices, sortFields)
+			}
+			if !emit(buffer, indices) {
+				return
+			}
+			buffer = make([]reflect.Value, 0, bufferLen)
+			indices = make([]int, 0, bufferLen)
+		}
+
+		if sortFields != nil {
+			sortItems(buffer, indices, sortFields)
+		}
+		// `BatchSizes` were already checked above, so this cannot fail
+		sizes, _ = getBatchSizes(len(buffer), batchLen, op.BatchSizes)
+		for _, limit := range sizes {
+			if !emit(buffer[:limit], indices[:limit]) {
+				return
+			}
+			buffer = buffer[limit:]
+			indices = indices[limit:]
+		}
 	}
+}
 
+// sqlGroup prepares the placeholders and arguments of the items, the first of which is the `idxBase`-th row sent.
+//          `indices` are where the items sit in the data when they were reordered, nil otherwise.
+//
+//          Cells sent as `DEFAULT` take no argument, so the placeholders are numbered by the arguments rather than by
+//          their position in the rows. These are the cells of map rows missing a key with `MissingKeyDefault`, and the
+//          zero or NULL cells of `DefaultWhenZero` and of the fields tagged `omitempty`. In an upsert, the update sets
+//          the columns to their default as well, through `excluded`, which is why these columns cannot be updated on
+//          conflict. See `BulkUpsert.WithDefaultWhenZero()`. Map rows missing the key of a column to update are
+//          rejected with `ErrMissingKey` for the same reason, since whether a key is there changes from one row to the
+//          next.
+func (op BulkInsert) sqlGroup(
+	fields []structField,
+	idxBase int,
+	items []reflect.Value,
+	indices []int,
+) (QueryGroup, error) {
 	fieldsCount := len(fields)
-	valueLen := op.DataValue.Len()
-	batchLen, batchCnt := getBatchingInfo(valueLen, fieldsCount, psqlMaxParamCount)
-
-	groups := make([]QueryGroup, 0, batchCnt)
-	for batchIdx := 0; batchIdx < batchCnt; batchIdx++ {
-		rows := make([]string, 0, batchLen)
-		args := make([]interface{}, 0, batchLen)
-		idxBase := batchLen * batchIdx
-
-		// if it's the last batch, it will likely be shorter than `batchLen`
-		limit := batchLen
-		if batchIdx >= batchCnt-1 {
-			limit = int(math.Min(float64(batchLen), float64(valueLen-batchIdx*batchLen)))
-		}
+	rows := make([]string, 0, len(items))
+	args := make([]interface{}, 0, len(items)*fieldsCount)
+	defaults := 0
+	now := GetCurrentTime()
+
+	defaultWhenZero := make([]bool, 0, fieldsCount)
+	for _, field := range fields {
+		defaultWhenZero = append(defaultWhenZero, field.OmitEmpty || slices.Contains(op.DefaultWhenZero, field.Column))
+	}
 
-		for rowIdx := 0; rowIdx < limit; rowIdx++ {
-			idx := rowIdx + idxBase
-			row := op.DataValue.Index(idx)
+	for rowIdx, row := range items {
+		// if we got passed an array of pointers to `orm.*` struct
+		if row.Kind() == reflect.Ptr {
+			row = row.Elem()
+		}
 
-			// if we got passed an array of pointers to `orm.*` struct
-			if row.Kind() == reflect.Ptr {
-				row = row.Elem()
+		cells := make([]string, 0, fieldsCount)
+		for fieldIdx, field := range fields {
+			value, found := getCellValue(row, field)
+			value, found = op.Timestamps.value(field.Column, value, found, now)
+			if found && defaultWhenZero[fieldIdx] && isZeroValue(value) {
+				cells = append(cells, "DEFAULT")
+				defaults++
+				continue
 			}
+			if !found {
+				dataIndex := idxBase + rowIdx
+				if indices != nil {
+					dataIndex = indices[rowIdx]
+				}
 
-			for _, field := range fields {
-				args = append(args, row.FieldByName(field).Interface())
+				switch {
+				case op.MissingKeys == MissingKeyDefault && slices.Contains(op.updated, field.Column):
+					return QueryGroup{}, pkgerrors.Wrapf(
+						ErrMissingKey,
+						"data %d: %q is updated on conflict, DEFAULT would reset it",
+						dataIndex,
+						field.Column,
+					)
+				case op.MissingKeys == MissingKeyDefault:
+					cells = append(cells, "DEFAULT")
+					defaults++
+					continue
+				case op.MissingKeys == MissingKeyError:
+					return QueryGroup{}, pkgerrors.Wrapf(ErrMissingKey, "data %d: %q", dataIndex, field.Column)
+				}
 			}
 
 			// psql placeholders are numbered vs mysql's "?"
-			rows = append(rows, strmangle.Placeholders(true, fieldsCount, fieldsCount*rowIdx+1, fieldsCount))
+			args = append(args, value)
+			cells = append(cells, "$"+strconv.Itoa(len(args)))
 		}
 
-		groups = append(groups, QueryGroup{
-			Rows:      rows,
-			Args:      args,
-			DataStart: idxBase,
-			DataEnd:   idxBase + limit,
-		})
+		rows = append(rows, "("+strings.Join(cells, ",")+")")
 	}
 
-	return groups, nil
+	return QueryGroup{
+		Rows:        rows,
+		Args:        args,
+		DataStart:   idxBase,
+		DataEnd:     idxBase + len(items),
+		DataIndices: indices,
+		items:       items,
+		defaults:    defaults,
+		tagNames:    op.TagNames,
+	}, nil
 }
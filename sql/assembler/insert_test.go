@@ -2,7 +2,11 @@ package assembler
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"iter"
+	"slices"
+	"strings"
 	"testing"
 
 	"code.in.spdigital.sg/sp-digital/athena/db/pg"
@@ -178,3 +182,331 @@ func TestBulkInsert_BindQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestBulkInsert_Batches(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	tcs := map[string]struct {
+		gvnRowsCount  int
+		gvnSource     string
+		gvnBatchSizes []int
+		gvnStopAfter  int
+		expRanges     [][2]int
+	}{
+		"success__all_batches": {
+			gvnRowsCount:  7,
+			gvnSource:     "slice",
+			gvnBatchSizes: []int{4, 2, 1},
+			gvnStopAfter:  -1,
+			expRanges:     [][2]int{{0, 4}, {4, 6}, {6, 7}},
+		},
+		"success__stop_early": {
+			gvnRowsCount:  7,
+			gvnSource:     "slice",
+			gvnBatchSizes: []int{4, 2, 1},
+			gvnStopAfter:  1,
+			expRanges:     [][2]int{{0, 4}},
+		},
+		"success__channel": {
+			gvnRowsCount:  7,
+			gvnSource:     "chan",
+			gvnBatchSizes: []int{4, 2, 1},
+			gvnStopAfter:  -1,
+			expRanges:     [][2]int{{0, 4}, {4, 6}, {6, 7}},
+		},
+		"success__iter_seq": {
+			gvnRowsCount:  9,
+			gvnSource:     "seq",
+			gvnBatchSizes: []int{4},
+			gvnStopAfter:  -1,
+			expRanges:     [][2]int{{0, 4}, {4, 8}, {8, 9}},
+		},
+		"success__iter_seq_stop_early": {
+			gvnRowsCount:  9,
+			gvnSource:     "seq",
+			gvnBatchSizes: []int{4},
+			gvnStopAfter:  2,
+			expRanges:     [][2]int{{0, 4}, {4, 8}},
+		},
+		"success__pull_func": {
+			gvnRowsCount:  5,
+			gvnSource:     "pull",
+			gvnBatchSizes: nil,
+			gvnStopAfter:  -1,
+			expRanges:     [][2]int{{0, 5}},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := make([]*SampleTable, 0, tc.gvnRowsCount)
+			for idx := 0; idx < tc.gvnRowsCount; idx++ {
+				data = append(data, &SampleTable{ID: int64(idx), Col01: fmt.Sprintf("DataRow__%d", idx)})
+			}
+
+			var source interface{}
+			switch tc.gvnSource {
+			case "chan":
+				channel := make(chan *SampleTable, len(data))
+				for _, row := range data {
+					channel <- row
+				}
+				close(channel)
+				source = channel
+			case "seq":
+				source = slices.Values(data)
+			case "pull":
+				next, stop := iter.Pull(slices.Values(data))
+				defer stop()
+				source = next
+			default:
+				source = data
+			}
+
+			op, err := NewBulkInsert(source, "sample", []string{"id", "col_01"})
+			require.NoError(t, err)
+			op.BatchSizes = tc.gvnBatchSizes
+
+			// When
+			ranges := make([][2]int, 0)
+			for group, err := range op.Batches() {
+				require.NoError(t, err)
+				require.NotNil(t, group.Query)
+				require.Len(t, group.Args, 2*(group.DataEnd-group.DataStart))
+				require.Equal(t, int64(group.DataStart), group.Args[0])
+
+				ranges = append(ranges, [2]int{group.DataStart, group.DataEnd})
+				if len(ranges) == tc.gvnStopAfter {
+					break
+				}
+			}
+
+			// Then
+			require.Equal(t, tc.expRanges, ranges)
+		})
+	}
+}
+
+func TestBulkInsert_DefaultWhenZero(t *testing.T) {
+	type SampleTable struct {
+		ID    int64         `boil:"id"`
+		Col01 string        `boil:"col_01"`
+		Col02 sql.NullInt64 `boil:"col_02"`
+	}
+
+	tcs := map[string]struct {
+		gvnDefaults []string
+		expRows     []string
+		expArgs     []interface{}
+		expErr      error
+	}{
+		"success__none": {
+			gvnDefaults: nil,
+			expRows:     []string{"($1,$2,$3)", "($4,$5,$6)"},
+			expArgs: []interface{}{
+				int64(0), "one", sql.NullInt64{},
+				int64(2), "", sql.NullInt64{Int64: 0, Valid: true},
+			},
+		},
+		"success__serial_id": {
+			gvnDefaults: []string{"id"},
+			expRows:     []string{"(DEFAULT,$1,$2)", "($3,$4,$5)"},
+			expArgs: []interface{}{
+				"one", sql.NullInt64{},
+				int64(2), "", sql.NullInt64{Int64: 0, Valid: true},
+			},
+		},
+		"success__several": {
+			gvnDefaults: []string{"id", "col_01", "col_02"},
+			expRows:     []string{"(DEFAULT,$1,DEFAULT)", "($2,DEFAULT,$3)"},
+			expArgs: []interface{}{
+				"one",
+				int64(2), sql.NullInt64{Int64: 0, Valid: true},
+			},
+		},
+		"failure__not_inserted": {
+			gvnDefaults: []string{"col_03"},
+			expErr:      ErrColumnInvalid,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []SampleTable{
+				{ID: 0, Col01: "one"},
+				{ID: 2, Col02: sql.NullInt64{Int64: 0, Valid: true}},
+			}
+
+			op, err := NewBulkInsert(data, "sample_table", nil)
+			require.NoError(t, err)
+			op.DefaultWhenZero = tc.gvnDefaults
+
+			// When
+			groups, err := op.Queries()
+
+			// Then
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expRows, groups[0].Rows)
+			require.Equal(t, tc.expArgs, groups[0].Args)
+			require.Equal(t, tc.expArgs, groups[0].Statement.Args)
+		})
+	}
+}
+
+func TestBulkInsert_OmitEmpty(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01,omitempty"`
+		Col02 string `boil:"col_02"`
+	}
+
+	tcs := map[string]struct {
+		gvnUpdate []string
+		expRows   []string
+		expArgs   []interface{}
+		expErr    error
+	}{
+		"success__default_when_zero": {
+			gvnUpdate: nil,
+			expRows:   []string{"($1,DEFAULT,$2)", "($3,$4,$5)"},
+			expArgs:   []interface{}{int64(1), "one", int64(2), "two", "two"},
+		},
+		"failure__updated": {
+			gvnUpdate: []string{"col_01"},
+			expErr:    ErrColumnInvalid,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []SampleTable{
+				{ID: 1, Col02: "one"},
+				{ID: 2, Col01: "two", Col02: "two"},
+			}
+
+			op, err := NewBulkUpsert(data, "sample_table", []string{"id"}, nil, tc.gvnUpdate)
+			require.NoError(t, err)
+
+			// When
+			groups, err := op.Queries()
+
+			// Then
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, []string{"id", "col_01", "col_02"}, op.Columns)
+			require.Equal(t, []string{"id", "col_02"}, op.ColumnsUpdate)
+			require.Equal(t, tc.expRows, groups[0].Rows)
+			require.Equal(t, tc.expArgs, groups[0].Args)
+		})
+	}
+}
+
+func TestBulkUpsert_DefaultWhenZero(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+		Col02 string `boil:"col_02"`
+	}
+
+	tcs := map[string]struct {
+		gvnThroughMethod bool
+		expUpdate        []string
+		expErr           error
+	}{
+		"success__taken_out_of_update": {
+			gvnThroughMethod: true,
+			expUpdate:        []string{"col_01", "col_02"},
+		},
+		"failure__updated_on_conflict": {
+			gvnThroughMethod: false,
+			expErr:           ErrColumnInvalid,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []SampleTable{
+				{ID: 0, Col01: "one", Col02: "a"},
+				{ID: 2, Col01: "two", Col02: "b"},
+			}
+
+			op, err := NewBulkUpsert(data, "sample_table", []string{"col_01"}, nil, nil)
+			require.NoError(t, err)
+			require.Equal(t, []string{"id", "col_01", "col_02"}, op.ColumnsUpdate)
+
+			if tc.gvnThroughMethod {
+				op = op.WithDefaultWhenZero("id")
+			} else {
+				op.DefaultWhenZero = []string{"id"}
+			}
+
+			// When
+			groups, err := op.Queries()
+
+			// Then
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expUpdate, op.ColumnsUpdate)
+			require.Equal(t, []string{"(DEFAULT,$1,$2)", "($3,$4,$5)"}, groups[0].Rows)
+			require.NotContains(t, groups[0].Statement.SQL, `"id" = "excluded"."id"`)
+		})
+	}
+}
+
+func TestBulkInsert_Returning(t *testing.T) {
+	type SampleTable struct {
+		ID       int64  `boil:"id"`
+		Col01    string `boil:"col_01"`
+		Computed string `boil:"computed"`
+	}
+
+	tcs := map[string]struct {
+		gvnReturningAll bool
+		expReturning    string
+	}{
+		"success__inserted_columns": {
+			gvnReturningAll: false,
+			expReturning:    `RETURNING "col_01"`,
+		},
+		"success__all_columns": {
+			gvnReturningAll: true,
+			expReturning:    `RETURNING "id","col_01","computed"`,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			op, err := NewBulkInsert([]SampleTable{{Col01: "one"}}, "sample_table", []string{"col_01"})
+			require.NoError(t, err)
+			op.ReturningAll = tc.gvnReturningAll
+
+			// When
+			groups, err := op.Queries()
+
+			// Then
+			require.NoError(t, err)
+			require.True(t, strings.HasSuffix(groups[0].Statement.SQL, tc.expReturning))
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package assembler
+
+import (
+	"reflect"
+	"slices"
+)
+
+/**
+ * Question: how do map rows line up with the columns?
+ * Answer: by their keys, which are the column names as they are. There are no tags to go by, so:
+ *             > inferring the columns takes every key found in the rows, sorted since maps have no order of their own.
+ *               Streams cannot be looked into up front, so their columns have to be named.
+ *             > any column goes, there is no way to tell that one is unknown before Postgres does
+ *             > rows do not need to have every key, see `MissingKey` for what is sent in their place
+ *
+ *         Everything else -- sorting, bisecting, binding the `RETURNING` rows into new maps -- works the same as with
+ *         structs.
+ */
+
+// MissingKey decides what is sent for the columns that a map row has no key for
+type MissingKey int
+
+const (
+	// MissingKeyNull sends NULL, which is the default
+	MissingKeyNull MissingKey = iota
+	// MissingKeyDefault sends the `DEFAULT` keyword for the database to fill in. Cannot go through `COPY`, nor be
+	// used for the columns an upsert updates, which fail with `ErrMissingKey` instead.
+	MissingKeyDefault
+	// MissingKeyError fails with `ErrMissingKey` before the batch is sent
+	MissingKeyError
+)
+
+// isMapType checks if the items are maps keyed by the column names
+func isMapType(itemType reflect.Type) bool {
+	return itemType.Kind() == reflect.Map && itemType.Key().Kind() == reflect.String
+}
+
+// getMapColumns lists every key found across the map rows, sorted. Streams have none since they cannot be read ahead.
+func getMapColumns(dataType reflect.Type, dataValue reflect.Value) []string {
+	if isStreamType(dataType) {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for idx := 0; idx < dataValue.Len(); idx++ {
+		iterator := getItem(dataValue.Index(idx)).MapRange()
+		for iterator.Next() {
+			seen[iterator.Key().String()] = true
+		}
+	}
+
+	columns := make([]string, 0, len(seen))
+	for column := range seen {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	return columns
+}
+
+// getMapFields gives every column a field of its own, since map rows hold whatever column they are asked for
+func getMapFields(columns []string) []structField {
+	fields := make([]structField, 0, len(columns))
+	for _, column := range columns {
+		fields = append(fields, structField{Name: column, Column: column})
+	}
+
+	return fields
+}
+
+// getMapValue reads the column out of the map row, reporting whether the key is there at all
+func getMapValue(item reflect.Value, column string) (interface{}, bool) {
+	value := item.MapIndex(reflect.ValueOf(column).Convert(item.Type().Key()))
+	if !value.IsValid() {
+		return nil, false
+	}
+
+	return value.Interface(), true
+}
+
+// getMapRow copies the map row over to a plain map, for the dead-letter sinks
+func getMapRow(item reflect.Value) map[string]interface{} {
+	output := make(map[string]interface{}, item.Len())
+	iterator := item.MapRange()
+	for iterator.Next() {
+		output[iterator.Key().String()] = iterator.Value().Interface()
+	}
+
+	return output
+}
+
+// scanMapRow reads the current row into a new map of `itemType`, with the values converted to its element type
+func scanMapRow(rows RowScanner, columns []string, itemType reflect.Type) (reflect.Value, error) {
+	targets := make([]interface{}, 0, len(columns))
+	for range columns {
+		targets = append(targets, reflect.New(itemType.Elem()).Interface())
+	}
+
+	if err := rows.Scan(targets...); err != nil {
+		return reflect.Value{}, err
+	}
+
+	item := reflect.MakeMapWithSize(itemType, len(columns))
+	for idx, column := range columns {
+		item.SetMapIndex(reflect.ValueOf(column).Convert(itemType.Key()), reflect.ValueOf(targets[idx]).Elem())
+	}
+
+	return item, nil
+}
@@ -0,0 +1,287 @@
+package assembler
+
+import (
+	"iter"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBulkInsert_MapRows(t *testing.T) {
+	tcs := map[string]struct {
+		gvnInterfaces  bool
+		gvnColumns     []string
+		gvnMissingKeys MissingKey
+		expColumns     []string
+		expRows        []string
+		expArgs        []interface{}
+		expErr         error
+	}{
+		"success__infer": {
+			gvnColumns: nil,
+			expColumns: []string{"col_01", "col_02", "id"},
+			expRows:    []string{"($1,$2,$3)", "($4,$5,$6)"},
+			expArgs:    []interface{}{"one", nil, 1, nil, "two", 2},
+		},
+		"success__infer_interfaces": {
+			gvnInterfaces: true,
+			gvnColumns:    nil,
+			expColumns:    []string{"col_01", "col_02", "id"},
+			expRows:       []string{"($1,$2,$3)", "($4,$5,$6)"},
+			expArgs:       []interface{}{"one", nil, 1, nil, "two", 2},
+		},
+		"success__missing_null": {
+			gvnColumns:     []string{"id", "col_01"},
+			gvnMissingKeys: MissingKeyNull,
+			expColumns:     []string{"id", "col_01"},
+			expRows:        []string{"($1,$2)", "($3,$4)"},
+			expArgs:        []interface{}{1, "one", 2, nil},
+		},
+		"success__missing_default": {
+			gvnColumns:     []string{"id", "col_01", "col_02"},
+			gvnMissingKeys: MissingKeyDefault,
+			expColumns:     []string{"id", "col_01", "col_02"},
+			expRows:        []string{"($1,$2,DEFAULT)", "($3,DEFAULT,$4)"},
+			expArgs:        []interface{}{1, "one", 2, "two"},
+		},
+		"failure__missing_error": {
+			gvnColumns:     []string{"id", "col_01"},
+			gvnMissingKeys: MissingKeyError,
+			expErr:         ErrMissingKey,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []map[string]interface{}{
+				{"id": 1, "col_01": "one"},
+				{"id": 2, "col_02": "two"},
+			}
+
+			var source interface{} = data
+			if tc.gvnInterfaces {
+				source = []interface{}{data[0], data[1]}
+			}
+
+			op, err := NewBulkInsert(source, "sample_table", tc.gvnColumns)
+			require.NoError(t, err)
+			op.MissingKeys = tc.gvnMissingKeys
+
+			// When
+			groups, err := op.Queries()
+
+			// Then
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				require.Contains(t, err.Error(), `data 1: "col_01"`)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expColumns, op.Columns)
+			require.Len(t, groups, 1)
+			require.Equal(t, tc.expRows, groups[0].Rows)
+			require.Equal(t, tc.expArgs, groups[0].Args)
+			require.Contains(t, groups[0].Statement.SQL, "RETURNING "+`"`+tc.expColumns[0]+`"`)
+		})
+	}
+}
+
+func TestNewBulkInsert_MapStream(t *testing.T) {
+	tcs := map[string]struct {
+		gvnColumns []string
+		expErr     error
+	}{
+		"success__named": {
+			gvnColumns: []string{"id"},
+		},
+		"failure__inferred": {
+			gvnColumns: nil,
+			expErr:     ErrMapColumns,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			var data iter.Seq[map[string]interface{}] = func(yield func(map[string]interface{}) bool) {
+				yield(map[string]interface{}{"id": 1})
+			}
+
+			// When
+			_, err := NewBulkInsert(data, "sample_table", tc.gvnColumns)
+
+			// Then
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNewBulkUpsert_MapRows(t *testing.T) {
+	// Given
+	data := []map[string]string{{"code": "one", "name": "One"}}
+
+	// When
+	op, err := NewBulkUpsert(data, "sample_table", []string{"code"}, nil, []string{"name"})
+	require.NoError(t, err)
+
+	groups, err := op.Queries()
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, []string{"code", "name"}, op.Columns)
+	require.Equal(t, []interface{}{"one", "One"}, groups[0].Args)
+	require.Contains(t, groups[0].Statement.SQL, "ON CONFLICT (\"code\")\nDO UPDATE SET\n    \"name\" = \"excluded\".\"name\"")
+}
+
+func TestNewBulkUpsert_MapRows_MissingDefault(t *testing.T) {
+	tcs := map[string]struct {
+		gvnUpdate []string
+		expRows   []string
+		expErr    error
+	}{
+		"success__missing_not_updated": {
+			gvnUpdate: []string{"name"},
+			expRows:   []string{"($1,$2,$3)", "($4,DEFAULT,$5)"},
+		},
+		"success__do_nothing": {
+			gvnUpdate: []string{},
+			expRows:   []string{"($1,$2,$3)", "($4,DEFAULT,$5)"},
+		},
+		"failure__missing_updated": {
+			gvnUpdate: []string{"name", "score"},
+			expErr:    ErrMissingKey,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []map[string]interface{}{
+				{"code": "one", "name": "One", "score": 1},
+				{"code": "two", "name": "Two"},
+			}
+
+			op, err := NewBulkUpsert(data, "sample_table", []string{"code"}, []string{"code", "score", "name"}, tc.gvnUpdate)
+			require.NoError(t, err)
+			op.MissingKeys = MissingKeyDefault
+
+			// When
+			groups, err := op.Queries()
+
+			// Then
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				require.Contains(t, err.Error(), `data 1: "score" is updated on conflict`)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expRows, groups[0].Rows)
+		})
+	}
+}
+
+func TestBulkInsert_SingleColumn(t *testing.T) {
+	type SampleTable struct {
+		ID int64 `boil:"id"`
+	}
+
+	// Given
+	op, err := NewBulkInsert([]SampleTable{{ID: 1}, {ID: 2}}, "sample_table", nil)
+	require.NoError(t, err)
+
+	// When
+	groups, err := op.Queries()
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, []string{"($1)", "($2)"}, groups[0].Rows)
+	require.Contains(t, groups[0].Statement.SQL, "VALUES\n($1),\n($2)\n")
+}
+
+func TestBulkInsert_Values(t *testing.T) {
+	tcs := map[string]struct {
+		gvnMissingKeys MissingKey
+		expValues      [][]interface{}
+		expErr         error
+	}{
+		"success__rows": {
+			gvnMissingKeys: MissingKeyNull,
+			expValues:      [][]interface{}{{1, nil}, {2, "two"}},
+		},
+		"failure__default": {
+			gvnMissingKeys: MissingKeyDefault,
+			expValues:      [][]interface{}{},
+			expErr:         ErrCopyDefault,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []map[string]interface{}{{"id": 1}, {"id": 2, "col_01": "two"}}
+			op, err := NewBulkInsert(data, "sample_table", []string{"id", "col_01"})
+			require.NoError(t, err)
+			op.MissingKeys = tc.gvnMissingKeys
+
+			// When
+			values := make([][]interface{}, 0, len(data))
+			for row, err := range op.Values() {
+				if err != nil {
+					require.ErrorIs(t, err, tc.expErr)
+					break
+				}
+				values = append(values, row)
+			}
+
+			// Then
+			require.Equal(t, tc.expValues, values)
+		})
+	}
+}
+
+// fakeMapRows yields the rows given, one at a time
+type fakeMapRows struct {
+	values [][]interface{}
+}
+
+func (rows *fakeMapRows) Next() bool {
+	return len(rows.values) > 0
+}
+
+func (rows *fakeMapRows) Scan(dest ...interface{}) error {
+	for idx, value := range rows.values[0] {
+		if value != nil {
+			reflect.ValueOf(dest[idx]).Elem().Set(reflect.ValueOf(value))
+		}
+	}
+	rows.values = rows.values[1:]
+
+	return nil
+}
+
+func (rows *fakeMapRows) Err() error {
+	return nil
+}
+
+func TestScanRows_Maps(t *testing.T) {
+	// Given
+	rows := &fakeMapRows{values: [][]interface{}{{int64(1), "one"}, {int64(2), nil}}}
+
+	// When
+	output, err := scanRows(rows, []string{"id", "col_01"}, reflect.TypeFor[map[string]interface{}](), nil)
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "col_01": "one"},
+		{"id": int64(2), "col_01": nil},
+	}, output.Interface())
+}
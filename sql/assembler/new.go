@@ -2,8 +2,10 @@ package assembler
 
 import (
 	"reflect"
+	"slices"
 
 	pkgerrors "github.com/pkg/errors"
+	"github.com/volatiletech/sqlboiler/v4/boil"
 )
 
 /**
@@ -19,82 +21,165 @@ import (
  * Question: does this library also autopopulate CreatedAt and UpdatedAt fields?
  * Answer: no. If you need this library, you're going to have to write it yourself. I am not sure if this is in scope of
  *         an SQL assembler-only package. SQLBoiler does it through the generated code.
+ *             -- update: it does when asked to after all, see `timestamps.go`
  *
  *         In fact, generating the `queries.Query` object in this library is already kinda sus.
+ *             -- update: every batch also comes with a plain `Statement` now, see `statement.go`
  */
 
-// NewBulkInsert creates a new instance that will help assemble a bulk INSERT SQL for Postgres
+// NewBulkInsert creates a new instance that will help assemble a bulk INSERT SQL for Postgres. The data may be an
+//               array or slice of structs, or a stream of them (see `isStreamType()`) which gets read one batch at a
+//               time. Leave `columns` nil to insert every column the struct has a `boil` tag for, see `columns.go`.
+//               Maps keyed by column are accepted as well, see `maprows.go`.
 func NewBulkInsert(
 	data interface{},
 	table string,
 	columns []string,
+	opts ...Option,
 ) (BulkInsert, error) {
-	dataType, dataValue, ok := isSupportedType(data)
-
-	if !ok {
-		return BulkInsert{}, pkgerrors.WithStack(ErrDataNotArray)
-	}
-
-	if dataValue.Len() <= 0 {
-		return BulkInsert{}, pkgerrors.WithStack(ErrDataEmpty)
-	}
-
-	item := dataValue.Index(0)
-	if item.Kind() == reflect.Ptr {
-		item = item.Elem()
-	}
-	if item.Kind() != reflect.Struct {
-		return BulkInsert{}, pkgerrors.WithStack(ErrDataNotStruct)
-	}
-
-	return BulkInsert{
-		Data:      data,
-		DataType:  dataType,
-		DataValue: dataValue,
-		Table:     table,
-		Columns:   columns,
-	}, nil
+	return NewBulkInsertWith(data, table, getSelection(columns), opts...)
 }
 
-// NewBulkUpsert creates a new instance that will help assemble a bulk INSERT ON CONFLICT SQL for Postgres
+// NewBulkUpsert creates a new instance that will help assemble a bulk INSERT ON CONFLICT SQL for Postgres. Accepts the
+//               same kinds of data as `NewBulkInsert()`, and infers `columnsInsert` the same way when it is nil.
+//               `columnsUpdate` falls back to the insert columns when nil, except for the ones tagged `pk` or
+//               `omitempty`.
 func NewBulkUpsert(
 	data interface{},
 	table string,
 	conflicts []string,
 	columnsInsert []string,
 	columnsUpdate []string,
+	opts ...Option,
 ) (BulkUpsert, error) {
-	dataType, dataValue, ok := isSupportedType(data)
+	op, err := NewBulkUpsertWith(
+		data,
+		table,
+		conflicts,
+		getSelection(columnsInsert),
+		boil.Whitelist(columnsUpdate...),
+		opts...,
+	)
+	if err != nil {
+		return BulkUpsert{}, err
+	}
+
+	if columnsUpdate == nil {
+		op.ColumnsUpdate = withoutColumns(op.Columns, getPrimaryKeyColumns(op.itemType(), op.TagNames))
+		op.ColumnsUpdate = withoutColumns(op.ColumnsUpdate, getOmitEmptyColumns(op.itemType(), op.TagNames))
+	}
 
+	return op, nil
+}
+
+// Option changes how the constructors read the data, see `TagNames()`
+type Option func(op *BulkInsert)
+
+// TagNames reads the columns from these struct tags rather than `boil`, in order of preference: each field goes with
+//          the first of them it has. E.g. `TagNames("boil", "db")` to take in `sqlx` structs as well.
+func TagNames(names ...string) Option {
+	names = slices.Clone(names)
+	return func(op *BulkInsert) {
+		op.TagNames = names
+	}
+}
+
+// getSupportedData validates the data given to the constructors. Streams can only be checked by the type of the items
+//                  they produce since reading them would consume them, while every item of a slice is checked up front
+//                  -- see `validateItems()`.
+func getSupportedData(data interface{}) (reflect.Type, reflect.Value, error) {
+	dataType, dataValue, ok := isSupportedType(data)
 	if !ok {
-		return BulkUpsert{}, pkgerrors.WithStack(ErrDataNotArray)
+		return nil, reflect.Value{}, pkgerrors.WithStack(ErrDataNotArray)
+	}
+
+	if isStreamType(dataType) {
+		if dataValue.IsNil() {
+			return nil, reflect.Value{}, pkgerrors.WithStack(ErrDataEmpty)
+		}
+
+		itemType := getItemType(dataType)
+		if itemType.Kind() == reflect.Ptr {
+			itemType = itemType.Elem()
+		}
+		if itemType.Kind() != reflect.Struct && !isMapType(getItemType(dataType)) {
+			return nil, reflect.Value{}, pkgerrors.WithStack(ErrDataNotStruct)
+		}
+
+		return dataType, dataValue, nil
 	}
 
 	if dataValue.Len() <= 0 {
-		return BulkUpsert{}, pkgerrors.WithStack(ErrDataEmpty)
+		return nil, reflect.Value{}, pkgerrors.WithStack(ErrDataEmpty)
+	}
+
+	if err := validateItems(dataValue); err != nil {
+		return nil, reflect.Value{}, err
+	}
+
+	return dataType, dataValue, nil
+}
+
+// validateItems makes sure that every item of the slice can be read from: structs, pointers to them or maps keyed by
+//               column, none of them nil, and all of the same type. Slices of `interface{}` are the only ones where the
+//               types can differ from one item to the next.
+func validateItems(dataValue reflect.Value) error {
+	// the type of the slice alone tells when none of the items will do
+	itemType := dataValue.Type().Elem()
+	if itemType.Kind() != reflect.Interface {
+		if itemType.Kind() == reflect.Ptr {
+			itemType = itemType.Elem()
+		}
+		if itemType.Kind() != reflect.Struct && !isMapType(dataValue.Type().Elem()) {
+			return pkgerrors.WithStack(ErrDataNotStruct)
+		}
+	}
+
+	var expected reflect.Type
+	for idx := 0; idx < dataValue.Len(); idx++ {
+		item := getItem(dataValue.Index(idx))
+		if err := validateItem(item); err != nil {
+			return pkgerrors.WithStack(ItemError{DataIndex: idx, Type: getItemTypeName(item), Err: err})
+		}
+
+		if expected == nil {
+			expected = item.Type()
+			continue
+		}
+		if item.Type() != expected {
+			return pkgerrors.WithStack(ItemError{
+				DataIndex: idx,
+				Type:      item.Type().String(),
+				Expected:  expected.String(),
+				Err:       ErrDataMixed,
+			})
+		}
+	}
+
+	return nil
+}
+
+// validateItem makes sure that a single item can be read from
+func validateItem(item reflect.Value) error {
+	if !item.IsValid() || (item.Kind() == reflect.Ptr || item.Kind() == reflect.Map) && item.IsNil() {
+		return ErrDataNil
 	}
 
-	item := dataValue.Index(0)
 	if item.Kind() == reflect.Ptr {
 		item = item.Elem()
 	}
-	if item.Kind() != reflect.Struct {
-		return BulkUpsert{}, pkgerrors.WithStack(ErrDataNotStruct)
+	if item.Kind() != reflect.Struct && !isMapType(item.Type()) {
+		return ErrDataNotStruct
 	}
 
-	if columnsUpdate == nil {
-		columnsUpdate = columnsInsert
+	return nil
+}
+
+// getItemTypeName describes the type of the item for the errors, which may not have one when it is a nil interface
+func getItemTypeName(item reflect.Value) string {
+	if !item.IsValid() {
+		return "nil"
 	}
 
-	return BulkUpsert{
-		BulkInsert: BulkInsert{
-			Data:      data,
-			DataType:  dataType,
-			DataValue: dataValue,
-			Table:     table,
-			Columns:   columnsInsert,
-		},
-		ColumnsUpdate:   columnsUpdate,
-		ConflictTargets: conflicts,
-	}, nil
+	return item.Type().String()
 }
@@ -1,6 +1,7 @@
 package assembler
 
 import (
+	"errors"
 	"testing"
 
 	"code.in.spdigital.sg/sp-digital/athena/db/pg"
@@ -261,3 +262,103 @@ func TestNewBulkUpsert(t *testing.T) {
 		})
 	}
 }
+
+func TestNewBulkInsert_Items(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	type OtherTable struct {
+		ID int64 `boil:"id"`
+	}
+
+	tcs := map[string]struct {
+		gvnData  interface{}
+		expErr   error
+		expIndex int
+		expMsg   string
+	}{
+		"success__interfaces": {
+			gvnData: []interface{}{&SampleTable{ID: 1}, &SampleTable{ID: 2}},
+		},
+		"failure__mixed_structs": {
+			gvnData:  []interface{}{SampleTable{ID: 1}, SampleTable{ID: 2}, OtherTable{ID: 3}},
+			expErr:   ErrDataMixed,
+			expIndex: 2,
+			expMsg:   "got assembler.OtherTable, expected assembler.SampleTable",
+		},
+		"failure__mixed_pointers": {
+			gvnData:  []interface{}{&SampleTable{ID: 1}, SampleTable{ID: 2}},
+			expErr:   ErrDataMixed,
+			expIndex: 1,
+		},
+		"failure__nil_pointer": {
+			gvnData:  []*SampleTable{{ID: 1}, nil},
+			expErr:   ErrDataNil,
+			expIndex: 1,
+			expMsg:   "data 1 (*assembler.SampleTable)",
+		},
+		"failure__nil_interface": {
+			gvnData:  []interface{}{SampleTable{ID: 1}, nil},
+			expErr:   ErrDataNil,
+			expIndex: 1,
+		},
+		"failure__not_struct": {
+			gvnData:  []interface{}{1, 2},
+			expErr:   ErrDataNotStruct,
+			expIndex: 0,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := tc.gvnData
+
+			// When
+			op, err := NewBulkInsert(data, "sample_table", nil)
+
+			// Then
+			if tc.expErr == nil {
+				require.NoError(t, err)
+				require.Equal(t, []string{"id", "col_01"}, op.Columns)
+
+				groups, err := op.Queries()
+				require.NoError(t, err)
+				require.Equal(t, []interface{}{int64(1), "", int64(2), ""}, groups[0].Args)
+				return
+			}
+
+			var itemErr ItemError
+			require.ErrorIs(t, err, tc.expErr)
+			require.True(t, errors.As(err, &itemErr))
+			require.Equal(t, tc.expIndex, itemErr.DataIndex)
+			require.Contains(t, err.Error(), tc.expMsg)
+		})
+	}
+}
+
+func TestBulkInsert_StreamNilItem(t *testing.T) {
+	type SampleTable struct {
+		ID int64 `boil:"id"`
+	}
+
+	// Given
+	data := make(chan *SampleTable, 2)
+	data <- &SampleTable{ID: 1}
+	data <- nil
+	close(data)
+
+	op, err := NewBulkInsert(data, "sample_table", nil)
+	require.NoError(t, err)
+
+	// When
+	_, err = op.Queries()
+
+	// Then
+	var itemErr ItemError
+	require.ErrorIs(t, err, ErrDataNil)
+	require.True(t, errors.As(err, &itemErr))
+	require.Equal(t, 1, itemErr.DataIndex)
+}
@@ -0,0 +1,228 @@
+package assembler
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"iter"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+/**
+ * Question: why sort at all?
+ * Answer: two upserts going through overlapping keys in different orders lock the rows in different orders, and end up
+ *         waiting on each other. Going through the keys in the same order everywhere avoids that.
+ *
+ *         Slices are sorted as a whole before they are split into batches. Streams can only be sorted one batch at a
+ *         time, which still keeps the locks within each batch in order.
+ *
+ *         Sorting is only done when asked for through `SortBy`, e.g. by the `ConflictTargets` of an upsert since those
+ *         are the keys being locked. It changes what `DataStart` and `DataEnd` index into, see `Order()`, so callers
+ *         mapping the batches back to the data by range have to go through `DataIndices` instead.
+ */
+
+// Order returns the position in the data of each row, in the order that they are sent to the database. `DataStart`
+//       and `DataEnd` of the batches, and of the ranges in `ExecResult`, index into this. Returns nil when the rows go
+//       out in the order they are given, which is always the case without `SortBy`, and for streams which are only
+//       sorted one batch at a time.
+//
+//       There is no need to call this to map the results back to the data: the batches and the results carry the
+//       positions of their own rows through `DataIndices`, see `DataRange.Indices()` and `BatchError.Indices()`.
+func (op BulkInsert) Order() ([]int, error) {
+	if len(op.SortBy) == 0 || isStreamType(op.DataType) {
+		return nil, nil
+	}
+
+	fields, err := op.sortFields()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]reflect.Value, 0, op.DataValue.Len())
+	order := make([]int, 0, op.DataValue.Len())
+	for idx := 0; idx < op.DataValue.Len(); idx++ {
+		items = append(items, getItem(op.DataValue.Index(idx)))
+		order = append(order, idx)
+	}
+
+	sortItems(items, order, fields)
+
+	return order, nil
+}
+
+// sortFields returns the struct fields to sort by, making sure that none of the columns went missing along the way
+func (op BulkInsert) sortFields() ([]structField, error) {
+	fields, err := getStructFields(op.itemType(), op.SortBy, op.TagNames)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) != len(op.SortBy) {
+		return nil, pkgerrors.Wrapf(ErrSortColumn, "sorting by %v", op.SortBy)
+	}
+
+	return fields, nil
+}
+
+// getItemsInOrder walks through the items of the slice in the given order
+func getItemsInOrder(dataValue reflect.Value, order []int) iter.Seq[reflect.Value] {
+	return func(yield func(reflect.Value) bool) {
+		for _, idx := range order {
+			if !yield(getItem(dataValue.Index(idx))) {
+				return
+			}
+		}
+	}
+}
+
+// sortItems sorts the items by the values of the fields, moving the indices along with them. Ties keep their order.
+func sortItems(items []reflect.Value, indices []int, fields []structField) {
+	keys := make([][]interface{}, 0, len(items))
+	for _, item := range items {
+		if item.Kind() == reflect.Ptr {
+			item = item.Elem()
+		}
+
+		key := make([]interface{}, 0, len(fields))
+		for _, field := range fields {
+			key = append(key, getFieldValue(item, field))
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Stable(itemSorter{items: items, indices: indices, keys: keys})
+}
+
+// itemSorter implements `sort.Interface` over the items along with their indices and keys
+type itemSorter struct {
+	items   []reflect.Value
+	indices []int
+	keys    [][]interface{}
+}
+
+func (sorter itemSorter) Len() int {
+	return len(sorter.items)
+}
+
+func (sorter itemSorter) Less(i, j int) bool {
+	for idx := range sorter.keys[i] {
+		if comparison := compareValues(sorter.keys[i][idx], sorter.keys[j][idx]); comparison != 0 {
+			return comparison < 0
+		}
+	}
+
+	return false
+}
+
+func (sorter itemSorter) Swap(i, j int) {
+	sorter.items[i], sorter.items[j] = sorter.items[j], sorter.items[i]
+	sorter.indices[i], sorter.indices[j] = sorter.indices[j], sorter.indices[i]
+	sorter.keys[i], sorter.keys[j] = sorter.keys[j], sorter.keys[i]
+}
+
+// compareValues compares two column values the way the database would for the common types, with NULLs first.
+//               `null.*` types and other `driver.Valuer`s are compared by their values. Anything else is compared by
+//               its text.
+func compareValues(left interface{}, right interface{}) int {
+	left, leftNull := comparableValue(left)
+	right, rightNull := comparableValue(right)
+
+	switch {
+	case leftNull && rightNull:
+		return 0
+	case leftNull:
+		return -1
+	case rightNull:
+		return 1
+	}
+
+	switch leftValue := left.(type) {
+	case int64:
+		if rightValue, ok := right.(int64); ok {
+			return compareOrdered(leftValue, rightValue)
+		}
+	case uint64:
+		if rightValue, ok := right.(uint64); ok {
+			return compareOrdered(leftValue, rightValue)
+		}
+	case float64:
+		if rightValue, ok := right.(float64); ok {
+			return compareOrdered(leftValue, rightValue)
+		}
+	case string:
+		if rightValue, ok := right.(string); ok {
+			return strings.Compare(leftValue, rightValue)
+		}
+	case bool:
+		if rightValue, ok := right.(bool); ok && leftValue != rightValue {
+			if leftValue {
+				return 1
+			}
+			return -1
+		}
+		return 0
+	case time.Time:
+		if rightValue, ok := right.(time.Time); ok {
+			return leftValue.Compare(rightValue)
+		}
+	case []byte:
+		if rightValue, ok := right.([]byte); ok {
+			return bytes.Compare(leftValue, rightValue)
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(left), fmt.Sprint(right))
+}
+
+// comparableValue unwraps the value into one of the types `compareValues()` knows about. Returns true for NULL.
+func comparableValue(value interface{}) (interface{}, bool) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		output, err := valuer.Value()
+		if err != nil {
+			return nil, true
+		}
+		value = output
+	}
+
+	reflected := reflect.ValueOf(value)
+	for reflected.Kind() == reflect.Ptr {
+		if reflected.IsNil() {
+			return nil, true
+		}
+		reflected = reflected.Elem()
+	}
+
+	switch reflected.Kind() {
+	case reflect.Invalid:
+		return nil, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflected.Int(), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflected.Uint(), false
+	case reflect.Float32, reflect.Float64:
+		return reflected.Float(), false
+	case reflect.String:
+		return reflected.String(), false
+	case reflect.Bool:
+		return reflected.Bool(), false
+	default:
+		return reflected.Interface(), false
+	}
+}
+
+// compareOrdered compares numbers
+func compareOrdered[T int64 | uint64 | float64](left T, right T) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,232 @@
+package assembler
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkInsert_Order(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+		Col02 int    `boil:"col_02"`
+	}
+
+	data := []SampleTable{
+		{ID: 0, Col01: "c", Col02: 2},
+		{ID: 1, Col01: "a", Col02: 1},
+		{ID: 2, Col01: "b", Col02: 1},
+		{ID: 3, Col01: "a", Col02: 0},
+		{ID: 4, Col01: "b", Col02: 0},
+	}
+
+	tcs := map[string]struct {
+		gvnSource     string
+		gvnSortBy     []string
+		gvnBatchSizes []int
+		expOrder      []int
+		expIndices    [][]int
+		expErr        bool
+	}{
+		"success__not_sorted": {
+			gvnSource:     "slice",
+			gvnSortBy:     nil,
+			gvnBatchSizes: []int{2, 1},
+			expOrder:      nil,
+			expIndices:    [][]int{{0, 1}, {2, 3}, {4}},
+		},
+		"success__sorted_single_column": {
+			gvnSource:     "slice",
+			gvnSortBy:     []string{"col_01"},
+			gvnBatchSizes: []int{2, 1},
+			expOrder:      []int{1, 3, 2, 4, 0},
+			expIndices:    [][]int{{1, 3}, {2, 4}, {0}},
+		},
+		"success__sorted_multiple_columns": {
+			gvnSource:     "slice",
+			gvnSortBy:     []string{"col_02", "col_01"},
+			gvnBatchSizes: []int{2, 1},
+			expOrder:      []int{3, 4, 1, 2, 0},
+			expIndices:    [][]int{{3, 4}, {1, 2}, {0}},
+		},
+		"success__sorted_per_batch_stream": {
+			gvnSource:     "seq",
+			gvnSortBy:     []string{"col_01"},
+			gvnBatchSizes: []int{2, 1},
+			expOrder:      nil,
+			expIndices:    [][]int{{1, 0}, {3, 2}, {4}},
+		},
+		"failure__unknown_column": {
+			gvnSource:     "slice",
+			gvnSortBy:     []string{"col_99"},
+			gvnBatchSizes: []int{2, 1},
+			expErr:        true,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			var source interface{} = data
+			if tc.gvnSource == "seq" {
+				source = slices.Values(data)
+			}
+
+			op, err := NewBulkInsert(source, "sample_table", []string{"id", "col_01", "col_02"})
+			require.NoError(t, err)
+			op.SortBy = tc.gvnSortBy
+			op.BatchSizes = tc.gvnBatchSizes
+
+			// When
+			order, err := op.Order()
+			groups, groupsErr := op.Queries()
+
+			// Then
+			if tc.expErr {
+				require.Error(t, err)
+				require.Error(t, groupsErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, groupsErr)
+			require.Equal(t, tc.expOrder, order)
+
+			indices := make([][]int, 0, len(groups))
+			for _, group := range groups {
+				batchIndices := make([]int, 0, group.DataEnd-group.DataStart)
+				for idx := 0; idx < group.DataEnd-group.DataStart; idx++ {
+					batchIndices = append(batchIndices, group.DataIndex(idx))
+					require.Equal(t, data[group.DataIndex(idx)].ID, group.Args[idx*3])
+				}
+				indices = append(indices, batchIndices)
+			}
+			require.Equal(t, tc.expIndices, indices)
+		})
+	}
+}
+
+func TestOrder_compareValues(t *testing.T) {
+	now := time.Now()
+
+	tcs := map[string]struct {
+		gvnLeft  interface{}
+		gvnRight interface{}
+		expValue int
+	}{
+		"success__ints":            {gvnLeft: 2, gvnRight: 10, expValue: -1},
+		"success__strings":         {gvnLeft: "b", gvnRight: "a", expValue: 1},
+		"success__times":           {gvnLeft: now, gvnRight: now.Add(time.Second), expValue: -1},
+		"success__null_types":      {gvnLeft: sql.NullInt64{Int64: 3, Valid: true}, gvnRight: sql.NullInt64{Int64: 3, Valid: true}, expValue: 0},
+		"success__null_first":      {gvnLeft: sql.NullInt64{}, gvnRight: sql.NullInt64{Int64: -5, Valid: true}, expValue: -1},
+		"success__nil_pointer":     {gvnLeft: &now, gvnRight: (*time.Time)(nil), expValue: 1},
+		"success__mismatched_type": {gvnLeft: "10", gvnRight: 9, expValue: -1},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// When
+			value := compareValues(tc.gvnLeft, tc.gvnRight)
+
+			// Then
+			require.Equal(t, tc.expValue, value)
+		})
+	}
+}
+
+func TestBulkUpsert_Exec_Sorted(t *testing.T) {
+	type SampleTable struct {
+		Code  string `boil:"code"`
+		Col01 string `boil:"col_01"`
+	}
+
+	data := []SampleTable{
+		{Code: "c", Col01: "DataRow__0"},
+		{Code: "a", Col01: "DataRow__1"},
+		{Code: "d", Col01: "DataRow__2"},
+		{Code: "b", Col01: "DataRow__3"},
+		{Code: "e", Col01: "DataRow__4"},
+	}
+
+	tcs := map[string]struct {
+		gvnSource    string
+		expSucceeded [][]int
+		expFailed    [][]int
+	}{
+		"success__slice": {
+			gvnSource:    "slice",
+			expSucceeded: [][]int{{1, 3}, {4}},
+			expFailed:    [][]int{{0, 2}},
+		},
+		"success__stream": {
+			gvnSource:    "seq",
+			expSucceeded: [][]int{{3, 2}, {4}},
+			expFailed:    [][]int{{1, 0}},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			var source interface{} = data
+			if tc.gvnSource == "seq" {
+				source = slices.Values(data)
+			}
+
+			op, err := NewBulkUpsert(source, "sample_table", []string{"code"}, nil, nil)
+			require.NoError(t, err)
+			op.BatchSizes = []int{2, 1}
+			op.SortBy = op.ConflictTargets
+
+			exec := &fakeExecutor{fail: failOnValue("DataRow__0")}
+
+			// When
+			result, err := op.ExecParallel(context.Background(), SharedConns(exec), ParallelOptions{
+				ContinueOnError: true,
+			})
+
+			// Then
+			require.ErrorIs(t, err, ErrBatchFailed)
+
+			succeeded := make([][]int, 0, len(result.Succeeded))
+			for _, dataRange := range result.Succeeded {
+				succeeded = append(succeeded, dataRange.Indices())
+			}
+			failed := make([][]int, 0, len(result.Failed))
+			for _, batchErr := range result.Failed {
+				failed = append(failed, batchErr.Indices())
+			}
+			require.Equal(t, tc.expSucceeded, succeeded)
+			require.Equal(t, tc.expFailed, failed)
+		})
+	}
+}
+
+func TestNewBulkUpsert_NotSorted(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	// Given
+	data := []SampleTable{{ID: 3, Col01: "three"}, {ID: 1, Col01: "one"}, {ID: 2, Col01: "two"}}
+
+	// When
+	op, err := NewBulkUpsert(data, "sample_table", []string{"id"}, nil, nil)
+	require.NoError(t, err)
+
+	groups, err := op.Queries()
+
+	// Then
+	require.NoError(t, err)
+	require.Nil(t, op.SortBy)
+	require.Equal(t, 0, groups[0].DataStart)
+	require.Equal(t, 3, groups[0].DataEnd)
+	require.Nil(t, groups[0].DataIndices)
+	require.Equal(t, []interface{}{int64(3), "three", int64(1), "one", int64(2), "two"}, groups[0].Args)
+}
@@ -0,0 +1,192 @@
+package assembler
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sort"
+	"sync"
+
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+/**
+ * Question: when is it safe to run batches concurrently?
+ * Answer: when the batches do not depend on each other -- e.g. plain inserts into unlogged staging tables. Concurrent
+ *         upserts over overlapping keys may deadlock each other, and there is no transaction spanning the batches, so
+ *         a failure leaves the batches that already went through committed.
+ */
+
+// ConnFactory hands out an executor for running a single batch, along with a function to give it back afterwards
+type ConnFactory func(ctx context.Context) (boil.ContextExecutor, func(), error)
+
+// ParallelOptions represents how the batches get executed by `ExecParallel()`
+type ParallelOptions struct {
+	// Concurrency is the most batches running at the same time. Defaults to 1.
+	Concurrency int
+	// Bind writes the `RETURNING` rows back into the data. See `ExecAndBind()`
+	Bind bool
+	// ContinueOnError keeps running the remaining batches after a failure instead of cancelling them
+	ContinueOnError bool
+	// Retry runs batches that failed for transient reasons again, see `retry.go`
+	Retry RetryPolicy
+	// DeadLetters receives the rows that could not be written once everything is done, see `deadletter.go`. Defaults to
+	// `BulkInsert.DeadLetters`.
+	DeadLetters DeadLetterSink
+}
+
+// SharedConns returns a `ConnFactory` that hands out the same executor every time. The executor must be safe for
+//             concurrent use, such as `*sql.DB`, which takes care of pooling the connections itself.
+func SharedConns(db boil.ContextExecutor) ConnFactory {
+	return func(context.Context) (boil.ContextExecutor, func(), error) {
+		return db, func() {}, nil
+	}
+}
+
+// ExecParallel runs the batches concurrently. See `execParallel()`
+func (op BulkInsert) ExecParallel(ctx context.Context, conns ConnFactory, opts ParallelOptions) (ExecResult, error) {
+	op.ReturningAll = op.ReturningAll || opts.Bind
+	if opts.DeadLetters == nil {
+		opts.DeadLetters = op.DeadLetters
+	}
+	return execParallel(ctx, conns, op.Batches(), opts)
+}
+
+// ExecParallel runs the batches concurrently. Overridden for the same reasons as `Queries()`
+func (op BulkUpsert) ExecParallel(ctx context.Context, conns ConnFactory, opts ParallelOptions) (ExecResult, error) {
+	if err := op.checkBind(opts.Bind); err != nil {
+		return ExecResult{}, err
+	}
+
+	op.ReturningAll = op.ReturningAll || opts.Bind
+	if opts.DeadLetters == nil {
+		opts.DeadLetters = op.DeadLetters
+	}
+	return execParallel(ctx, conns, op.Batches(), opts)
+}
+
+// execParallel runs up to `opts.Concurrency` batches at a time, each with an executor from `conns`. Batches are only
+//              built when there is room to run them. The result is ordered by data index regardless of the order the
+//              batches finished in.
+//
+//              Unless `opts.ContinueOnError` is set, the first failure cancels everything else and is returned as it is.
+//              Otherwise, every batch is attempted and `ErrBatchFailed` is returned if any of them failed. Batches cut
+//              short by the cancellation are left out of both `Succeeded` and `Failed`, the same as the batches never
+//              started: nothing is wrong with their rows, so they are not dead letters either.
+func execParallel(
+	ctx context.Context,
+	conns ConnFactory,
+	batches iter.Seq2[QueryGroup, error],
+	opts ParallelOptions,
+) (ExecResult, error) {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// the caller's `ctx` is kept apart for the dead letters, which are sent after everything else was cancelled
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mutex    sync.Mutex
+		wg       sync.WaitGroup
+		result   ExecResult
+		firstErr error
+	)
+
+	// record keeps the outcome of a batch, and cancels the rest if we're stopping at the first failure
+	record := func(group QueryGroup, attempts int, outcome groupOutcome, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		result.Retries += attempts - 1
+		if err == nil {
+			result.succeed(group, outcome)
+			return
+		}
+
+		// cancelled because another batch failed, or because the caller's context ended
+		if runCtx.Err() != nil && errors.Is(err, runCtx.Err()) {
+			return
+		}
+
+		batchErr := result.failAfter(group, attempts, err)
+		if firstErr == nil {
+			firstErr = batchErr
+			if !opts.ContinueOnError {
+				cancel()
+			}
+		}
+	}
+
+	slots := make(chan struct{}, concurrency)
+	buildErr := func() error {
+		for group, err := range batches {
+			if err != nil {
+				return err
+			}
+
+			if runCtx.Err() != nil {
+				return nil
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-runCtx.Done():
+				return nil
+			}
+
+			mutex.Lock()
+			result.Batches++
+			mutex.Unlock()
+
+			wg.Add(1)
+			go func(group QueryGroup) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				exec, release, err := conns(runCtx)
+				if err != nil {
+					record(group, 1, groupOutcome{}, err)
+					return
+				}
+				defer release()
+
+				var outcome groupOutcome
+				attempts, err := opts.Retry.do(runCtx, func() error {
+					var err error
+					outcome, err = execGroup(runCtx, exec, group, opts.Bind)
+					return err
+				})
+				record(group, attempts, outcome, err)
+			}(group)
+		}
+
+		return nil
+	}()
+
+	wg.Wait()
+
+	sort.Slice(result.Succeeded, func(i, j int) bool {
+		return result.Succeeded[i].Start < result.Succeeded[j].Start
+	})
+	sort.Slice(result.Failed, func(i, j int) bool {
+		return result.Failed[i].DataStart < result.Failed[j].DataStart
+	})
+
+	var err error
+	switch {
+	case buildErr != nil:
+		err = buildErr
+	case firstErr != nil && !opts.ContinueOnError:
+		err = firstErr
+	case result.err() != nil:
+		err = result.err()
+	default:
+		// in case the caller's context ended before we could go through everything
+		err = ctx.Err()
+	}
+
+	return sendDeadLetters(ctx, opts.DeadLetters, result, err)
+}
@@ -0,0 +1,181 @@
+package assembler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExecutor pretends to run the statements, failing those with an argument for which `fail` returns an error. Only
+// the statements for which `wait` returns true take `delay`, or all of them without `wait`.
+type fakeExecutor struct {
+	delay   time.Duration
+	wait    func(args []interface{}) bool
+	fail    func(args []interface{}) error
+	running int32
+	peak    int32
+	mutex   sync.Mutex
+	calls   int
+}
+
+func (exec *fakeExecutor) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return exec.ExecContext(context.Background(), query, args...)
+}
+
+func (exec *fakeExecutor) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (exec *fakeExecutor) QueryRow(query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (exec *fakeExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	running := atomic.AddInt32(&exec.running, 1)
+	defer atomic.AddInt32(&exec.running, -1)
+
+	for peak := atomic.LoadInt32(&exec.peak); running > peak; peak = atomic.LoadInt32(&exec.peak) {
+		if atomic.CompareAndSwapInt32(&exec.peak, peak, running) {
+			break
+		}
+	}
+
+	exec.mutex.Lock()
+	exec.calls++
+	exec.mutex.Unlock()
+
+	if exec.wait == nil || exec.wait(args) {
+		select {
+		case <-time.After(exec.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if exec.fail != nil {
+		if err := exec.fail(args); err != nil {
+			return nil, err
+		}
+	}
+
+	return driver.RowsAffected(strings.Count(query, "),\n(") + 1), nil
+}
+
+func (exec *fakeExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (exec *fakeExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+// failOnValue fails the statements that have `value` for an argument
+func failOnValue(value string) func(args []interface{}) error {
+	return func(args []interface{}) error {
+		for _, arg := range args {
+			if arg == value {
+				return fmt.Errorf("bad value %s", value)
+			}
+		}
+		return nil
+	}
+}
+
+func TestBulkInsert_ExecParallel(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	tcs := map[string]struct {
+		gvnConcurrency     int
+		gvnContinueOnError bool
+		gvnBadRow          int
+		expSucceeded       []DataRange
+		expFailed          []int
+		expErr             error
+	}{
+		"success__concurrent": {
+			gvnConcurrency: 3,
+			gvnBadRow:      -1,
+			expSucceeded: []DataRange{
+				{Start: 0, End: 10}, {Start: 10, End: 20}, {Start: 20, End: 30},
+				{Start: 30, End: 40}, {Start: 40, End: 50}, {Start: 50, End: 60},
+			},
+		},
+		"failure__continue_on_error": {
+			gvnConcurrency:     2,
+			gvnContinueOnError: true,
+			gvnBadRow:          25,
+			expSucceeded: []DataRange{
+				{Start: 0, End: 10}, {Start: 10, End: 20},
+				{Start: 30, End: 40}, {Start: 40, End: 50}, {Start: 50, End: 60},
+			},
+			expFailed: []int{20},
+			expErr:    ErrBatchFailed,
+		},
+		"failure__cancel_on_error": {
+			gvnConcurrency: 1,
+			gvnBadRow:      5,
+			expSucceeded:   nil,
+			expFailed:      []int{0},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			ctx := context.Background()
+
+			data := make([]*SampleTable, 0, 60)
+			for idx := 0; idx < 60; idx++ {
+				data = append(data, &SampleTable{ID: int64(idx), Col01: fmt.Sprintf("DataRow__%d", idx)})
+			}
+			if tc.gvnBadRow >= 0 {
+				data[tc.gvnBadRow].Col01 = "BadRow"
+			}
+
+			exec := &fakeExecutor{delay: time.Millisecond, fail: failOnValue("BadRow")}
+
+			op, err := NewBulkInsert(data, "sample", []string{"id", "col_01"})
+			require.NoError(t, err)
+			op.BatchSizes = []int{10}
+
+			// When
+			result, err := op.ExecParallel(ctx, SharedConns(exec), ParallelOptions{
+				Concurrency:     tc.gvnConcurrency,
+				ContinueOnError: tc.gvnContinueOnError,
+			})
+
+			// Then
+			require.LessOrEqual(t, int(exec.peak), tc.gvnConcurrency)
+			require.Equal(t, tc.expSucceeded, result.Succeeded)
+
+			failed := make([]int, 0, len(result.Failed))
+			for _, batchErr := range result.Failed {
+				failed = append(failed, batchErr.DataStart)
+			}
+			require.ElementsMatch(t, tc.expFailed, failed)
+
+			switch {
+			case tc.expFailed == nil:
+				require.NoError(t, err)
+			case tc.expErr != nil:
+				require.True(t, errors.Is(err, tc.expErr))
+			default:
+				var batchErr BatchError
+				require.True(t, errors.As(err, &batchErr))
+				require.Equal(t, tc.expFailed[0], batchErr.DataStart)
+			}
+		})
+	}
+}
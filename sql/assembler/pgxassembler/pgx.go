@@ -0,0 +1,191 @@
+package pgxassembler
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"github.com/aeroheart-c6/golang-SQwole/sql/assembler"
+	"github.com/jackc/pgx/v5"
+	pkgerrors "github.com/pkg/errors"
+)
+
+/**
+ * Question: why a package of its own?
+ * Answer: so that `assembler` does not drag `pgx` along for everyone going through `database/sql`. The batches are
+ *         built by the operation as usual and handed over through `ExecWith()`, see `runner.go` over there.
+ *
+ * Question: what does sending everything as a `pgx.Batch` buy us?
+ * Answer: a single round trip. Every statement is queued up front and pipelined over the connection, which matters
+ *         the most when the database is far away. In exchange, every batch is built and held in memory at the same
+ *         time, and everything runs in one implicit transaction: a failing statement takes the rest down with it
+ *         unless `conn` is already in a transaction of its own.
+ *
+ * Question: why can't upserts go through `COPY`?
+ * Answer: `COPY` has no `ON CONFLICT`, which is why `CopyFrom()` only takes a `BulkInsert`. Copy into a staging table
+ *         and upsert from there instead.
+ *
+ * Question: what about the hooks?
+ * Answer: they take a `boil.ContextExecutor`, which `pgx` is not. Rather than skipping them quietly, operations with
+ *         hooks are turned down with `ErrHooks`.
+ */
+
+// ErrHooks when running hooks through `pgx`, which has no `boil.ContextExecutor` to give them
+var ErrHooks = errors.New("hooks cannot run through pgx")
+
+// Conn represents what is needed out of `pgx`, satisfied by `*pgx.Conn`, `*pgxpool.Pool` and `pgx.Tx`
+type Conn interface {
+	SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults
+	CopyFrom(
+		ctx context.Context,
+		tableName pgx.Identifier,
+		columnNames []string,
+		rowSrc pgx.CopyFromSource,
+	) (int64, error)
+}
+
+// Operation represents either `assembler.BulkInsert` or `assembler.BulkUpsert`
+type Operation interface {
+	ExecWith(ctx context.Context, runner assembler.GroupRunner, bind bool) (assembler.ExecResult, error)
+}
+
+// Exec runs every batch through a single `pgx.Batch` without reading back the `RETURNING` rows. See `RunGroups()`
+func Exec(ctx context.Context, conn Conn, op Operation) (assembler.ExecResult, error) {
+	return op.ExecWith(ctx, batchRunner{conn: conn}, false)
+}
+
+// ExecAndBind runs every batch through a single `pgx.Batch` and writes the `RETURNING` rows back into the data
+func ExecAndBind(ctx context.Context, conn Conn, op Operation) (assembler.ExecResult, error) {
+	return op.ExecWith(ctx, batchRunner{conn: conn}, true)
+}
+
+// CopyFrom writes every row through `COPY`, which skips the statement size limit and the `RETURNING` rows
+//          altogether. The values are extracted the same way as for the batches, in the same order.
+func CopyFrom(ctx context.Context, conn Conn, op assembler.BulkInsert) (int64, error) {
+	if op.Hooks != nil {
+		return 0, pkgerrors.WithStack(ErrHooks)
+	}
+
+	next, stop := iter.Pull2(op.Values())
+	defer stop()
+
+	source := &copySource{next: next}
+	count, err := conn.CopyFrom(ctx, pgx.Identifier{op.Table}, op.Columns, source)
+	if source.err != nil {
+		return count, source.err
+	}
+
+	return count, pkgerrors.WithStack(err)
+}
+
+// batchRunner runs the batches of an operation through `pgx`
+type batchRunner struct {
+	conn Conn
+}
+
+// RunGroups queues every batch into one `pgx.Batch` and reads the results back in the same order. Like
+//           `TxAllOrNothing`, a failure means that nothing went through, unless `conn` is a transaction which the
+//           caller carries on with.
+func (runner batchRunner) RunGroups(
+	ctx context.Context,
+	batches iter.Seq2[assembler.QueryGroup, error],
+	bind bool,
+) (assembler.ExecResult, error) {
+	groups := make([]assembler.QueryGroup, 0)
+	for group, err := range batches {
+		if err != nil {
+			return assembler.ExecResult{}, err
+		}
+		groups = append(groups, group)
+	}
+	if len(groups) > 0 && groups[0].HasHooks() {
+		return assembler.ExecResult{}, pkgerrors.WithStack(ErrHooks)
+	}
+
+	batch := &pgx.Batch{}
+	for _, group := range groups {
+		batch.Queue(group.Statement.SQL, group.Statement.Args...)
+	}
+
+	results := runner.conn.SendBatch(ctx, batch)
+	defer results.Close()
+
+	result := assembler.ExecResult{Batches: len(groups)}
+	for _, group := range groups {
+		affected, err := execGroup(results, group, bind)
+		if err != nil {
+			// nothing made it in after all
+			result.RowsAffected = 0
+			result.Succeeded = nil
+			return result, result.Fail(group, err)
+		}
+
+		result.Succeed(group, affected)
+	}
+
+	if err := results.Close(); err != nil {
+		result.RowsAffected = 0
+		result.Succeeded = nil
+		return result, pkgerrors.WithStack(err)
+	}
+
+	return result, nil
+}
+
+// execGroup reads the result of a single batch. When binding, the number of rows returned is reported as the rows
+//           affected.
+func execGroup(results pgx.BatchResults, group assembler.QueryGroup, bind bool) (int64, error) {
+	if !bind {
+		tag, err := results.Exec()
+		if err != nil {
+			return 0, err
+		}
+
+		return tag.RowsAffected(), nil
+	}
+
+	rows, err := results.Query()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns := make([]string, 0, len(rows.FieldDescriptions()))
+	for _, field := range rows.FieldDescriptions() {
+		columns = append(columns, field.Name)
+	}
+
+	return group.BindRows(rows, columns)
+}
+
+// copySource walks through the values of the rows one at a time for `CopyFrom()`
+type copySource struct {
+	next   func() ([]interface{}, error, bool)
+	values []interface{}
+	err    error
+}
+
+// Next implements `pgx.CopyFromSource`
+func (source *copySource) Next() bool {
+	values, err, ok := source.next()
+	if !ok {
+		return false
+	}
+	if err != nil {
+		source.err = err
+		return false
+	}
+
+	source.values = values
+	return true
+}
+
+// Values implements `pgx.CopyFromSource`
+func (source *copySource) Values() ([]interface{}, error) {
+	return source.values, nil
+}
+
+// Err implements `pgx.CopyFromSource`
+func (source *copySource) Err() error {
+	return source.err
+}
@@ -0,0 +1,214 @@
+package pgxassembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aeroheart-c6/golang-SQwole/sql/assembler"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePgxConn pretends to run the queued statements in order, failing the first one for which `fail` returns an error
+//             along with every statement after it
+type fakePgxConn struct {
+	fail   func(args []interface{}) error
+	copied [][]interface{}
+}
+
+func (conn *fakePgxConn) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	return &fakeBatchResults{conn: conn, queued: batch.QueuedQueries}
+}
+
+func (conn *fakePgxConn) CopyFrom(
+	ctx context.Context,
+	tableName pgx.Identifier,
+	columnNames []string,
+	rowSrc pgx.CopyFromSource,
+) (int64, error) {
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return 0, err
+		}
+		if len(values) != len(columnNames) {
+			return 0, fmt.Errorf("expected %d values, got %d", len(columnNames), len(values))
+		}
+		conn.copied = append(conn.copied, append([]interface{}{}, values...))
+	}
+
+	return int64(len(conn.copied)), rowSrc.Err()
+}
+
+// fakeBatchResults hands out the results of the queued statements one at a time
+type fakeBatchResults struct {
+	conn   *fakePgxConn
+	queued []*pgx.QueuedQuery
+	failed error
+}
+
+func (results *fakeBatchResults) Exec() (pgconn.CommandTag, error) {
+	query := results.queued[0]
+	results.queued = results.queued[1:]
+
+	if results.failed == nil && results.conn.fail != nil {
+		results.failed = results.conn.fail(query.Arguments)
+	}
+	if results.failed != nil {
+		return pgconn.CommandTag{}, results.failed
+	}
+
+	return pgconn.NewCommandTag(fmt.Sprintf("INSERT 0 %d", len(query.Arguments)/2)), nil
+}
+
+func (results *fakeBatchResults) Query() (pgx.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (results *fakeBatchResults) QueryRow() pgx.Row {
+	return nil
+}
+
+func (results *fakeBatchResults) Close() error {
+	return nil
+}
+
+// failOnValue fails the statements holding the value
+func failOnValue(value string) func(args []interface{}) error {
+	return func(args []interface{}) error {
+		for _, arg := range args {
+			if arg == value {
+				return fmt.Errorf("bad value %s", value)
+			}
+		}
+		return nil
+	}
+}
+
+func TestExec(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	tcs := map[string]struct {
+		gvnFail      func(args []interface{}) error
+		expSucceeded []assembler.DataRange
+		expFailed    []assembler.DataRange
+		expAffected  int64
+	}{
+		"success__all_batches": {
+			gvnFail:      nil,
+			expSucceeded: []assembler.DataRange{{Start: 0, End: 4}, {Start: 4, End: 8}, {Start: 8, End: 10}},
+			expFailed:    []assembler.DataRange{},
+			expAffected:  10,
+		},
+		"failure__second_batch": {
+			gvnFail:      failOnValue("DataRow__5"),
+			expSucceeded: nil,
+			expFailed:    []assembler.DataRange{{Start: 4, End: 8}},
+			expAffected:  0,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := make([]*SampleTable, 0, 10)
+			for idx := 0; idx < 10; idx++ {
+				data = append(data, &SampleTable{ID: int64(idx), Col01: fmt.Sprintf("DataRow__%d", idx)})
+			}
+
+			op, err := assembler.NewBulkInsert(data, "sample_table", []string{"id", "col_01"})
+			require.NoError(t, err)
+			op.BatchSizes = []int{4, 2}
+
+			// When
+			result, err := Exec(context.Background(), &fakePgxConn{fail: tc.gvnFail}, op)
+
+			// Then
+			failed := make([]assembler.DataRange, 0, len(result.Failed))
+			for _, batchErr := range result.Failed {
+				failed = append(failed, assembler.DataRange{Start: batchErr.DataStart, End: batchErr.DataEnd})
+			}
+
+			if len(tc.expFailed) > 0 {
+				var batchErr assembler.BatchError
+				require.ErrorAs(t, err, &batchErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, 3, result.Batches)
+			require.Equal(t, tc.expSucceeded, result.Succeeded)
+			require.Equal(t, tc.expFailed, failed)
+			require.Equal(t, tc.expAffected, result.RowsAffected)
+		})
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+		Col02 string `boil:"col_02"`
+	}
+
+	// Given
+	data := make([]SampleTable, 0, 5)
+	for idx := 0; idx < 5; idx++ {
+		data = append(data, SampleTable{ID: int64(idx), Col01: fmt.Sprintf("DataRow__%d", idx), Col02: "ignored"})
+	}
+
+	op, err := assembler.NewBulkInsert(data, "sample_table", []string{"id", "col_01"})
+	require.NoError(t, err)
+	op.BatchSizes = []int{2}
+	op.SortBy = []string{"id"}
+
+	conn := &fakePgxConn{}
+
+	// When
+	count, err := CopyFrom(context.Background(), conn, op)
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, int64(5), count)
+	for idx, values := range conn.copied {
+		require.Equal(t, []interface{}{data[idx].ID, data[idx].Col01}, values)
+	}
+}
+
+func TestCopyFrom_Default(t *testing.T) {
+	// Given
+	data := []map[string]interface{}{{"id": 1}, {"id": 2, "col_01": "two"}}
+	op, err := assembler.NewBulkInsert(data, "sample_table", []string{"id", "col_01"})
+	require.NoError(t, err)
+	op.MissingKeys = assembler.MissingKeyDefault
+
+	// When
+	_, err = CopyFrom(context.Background(), &fakePgxConn{}, op)
+
+	// Then
+	require.ErrorIs(t, err, assembler.ErrCopyDefault)
+}
+
+func TestHooks(t *testing.T) {
+	type SampleTable struct {
+		ID int64 `boil:"id"`
+	}
+
+	// Given
+	op, err := assembler.NewBulkInsert([]SampleTable{{ID: 1}}, "sample_table", nil)
+	require.NoError(t, err)
+	op.Hooks = &assembler.Hooks{Rows: true}
+
+	// When
+	_, execErr := Exec(context.Background(), &fakePgxConn{}, op)
+	_, copyErr := CopyFrom(context.Background(), &fakePgxConn{}, op)
+
+	// Then
+	require.ErrorIs(t, execErr, ErrHooks)
+	require.ErrorIs(t, copyErr, ErrHooks)
+}
@@ -0,0 +1,118 @@
+package assembler
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+/**
+ * Question: where do the retries happen?
+ * Answer: as close to the failing batch as possible, so only that batch is run again:
+ *             > `ExecParallel()` and `TxPerBatch` begin the batch over (in a new transaction for the latter) since a
+ *               serialization failure dooms the transaction it happened in
+ *             > `TxSavepoint` rolls back to the batch's savepoint and runs it again. This gets around deadlocks, but
+ *               not serialization failures under REPEATABLE READ or SERIALIZABLE since the snapshot stays the same
+ *             > `TxAllOrNothing` never retries, the transaction is already gone by the time we'd want to
+ *             > `Exec()` and `ExecAndBind()` never retry either, they take no options. Use `ExecParallel()` with a
+ *               `Concurrency` of 1 to run the batches one after the other with retries.
+ *
+ *         With `Bisect` on, a batch failing with an error that is going to be retried is run again as a whole rather
+ *         than bisected. When retrying is off, every failing batch is bisected regardless of its error.
+ */
+
+const (
+	pgCodeSerializationFailure = "40001"
+	pgCodeDeadlockDetected     = "40P01"
+
+	defaultRetryBaseDelay = 50 * time.Millisecond
+	defaultRetryMaxDelay  = 5 * time.Second
+)
+
+// RetryPolicy represents how batches that failed for transient reasons are retried
+type RetryPolicy struct {
+	// MaxAttempts is the most times a batch is run, including the first. Zero or one disables retrying.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, doubling on every retry after. Defaults to 50ms.
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between retries. Defaults to 5s.
+	MaxDelay time.Duration
+	// Retryable decides whether the error is worth retrying. Defaults to `IsRetryable()`
+	Retryable func(err error) bool
+}
+
+// IsRetryable checks if the error is a serialization failure or a deadlock, either of which can go away on its own
+func IsRetryable(err error) bool {
+	pgErr, ok := asPgError(err)
+	if !ok {
+		return false
+	}
+
+	return pgErr.Code == pgCodeSerializationFailure || pgErr.Code == pgCodeDeadlockDetected
+}
+
+// do calls `fn` until it succeeds, fails with an error that isn't retryable, or runs out of attempts. Waits in between
+//    with exponential backoff and jitter, but never past the context's deadline. Returns the number of attempts made.
+func (policy RetryPolicy) do(ctx context.Context, fn func() error) (int, error) {
+	attempts := 0
+	for {
+		attempts++
+		err := fn()
+		if err == nil || attempts >= policy.MaxAttempts || !policy.retryable(err) {
+			return attempts, err
+		}
+
+		delay := policy.delay(attempts)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return attempts, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts, err
+		case <-timer.C:
+		}
+	}
+}
+
+// retries checks if the policy is going to retry the error at all, which it never does when retrying is disabled
+func (policy RetryPolicy) retries(err error) bool {
+	return policy.MaxAttempts > 1 && policy.retryable(err)
+}
+
+// retryable decides whether the error is worth retrying. Failing savepoints never are, the transaction is unusable.
+func (policy RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, errSavepoint) {
+		return false
+	}
+
+	if policy.Retryable != nil {
+		return policy.Retryable(err)
+	}
+
+	return IsRetryable(err)
+}
+
+// delay returns how long to wait after the given attempt: somewhere between half and all of the exponential backoff
+func (policy RetryPolicy) delay(attempt int) time.Duration {
+	base := policy.BaseDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+
+	limit := policy.MaxDelay
+	if limit <= 0 {
+		limit = defaultRetryMaxDelay
+	}
+
+	delay := limit
+	if shift := attempt - 1; shift < 32 && base<<shift < limit {
+		delay = base << shift
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
@@ -0,0 +1,181 @@
+package assembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_do(t *testing.T) {
+	serializationErr := &pqError{Code: pgCodeSerializationFailure}
+	deadlockErr := &pgconnError{Code: pgCodeDeadlockDetected}
+	uniqueErr := &pqError{Code: "23505"}
+
+	tcs := map[string]struct {
+		gvnPolicy   RetryPolicy
+		gvnErrs     []error
+		gvnDeadline time.Duration
+		expAttempts int
+		expErr      error
+	}{
+		"success__first_attempt": {
+			gvnPolicy:   RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+			gvnErrs:     nil,
+			expAttempts: 1,
+		},
+		"success__after_serialization_failure_and_deadlock": {
+			gvnPolicy:   RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+			gvnErrs:     []error{serializationErr, deadlockErr},
+			expAttempts: 3,
+		},
+		"failure__out_of_attempts": {
+			gvnPolicy:   RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond},
+			gvnErrs:     []error{deadlockErr, deadlockErr, deadlockErr},
+			expAttempts: 2,
+			expErr:      deadlockErr,
+		},
+		"failure__not_retryable": {
+			gvnPolicy:   RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+			gvnErrs:     []error{uniqueErr},
+			expAttempts: 1,
+			expErr:      uniqueErr,
+		},
+		"failure__retries_disabled": {
+			gvnPolicy:   RetryPolicy{},
+			gvnErrs:     []error{deadlockErr},
+			expAttempts: 1,
+			expErr:      deadlockErr,
+		},
+		"failure__deadline_too_close": {
+			gvnPolicy:   RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second},
+			gvnErrs:     []error{deadlockErr},
+			gvnDeadline: 100 * time.Millisecond,
+			expAttempts: 1,
+			expErr:      deadlockErr,
+		},
+		"success__custom_classification": {
+			gvnPolicy: RetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				Retryable:   func(err error) bool { return true },
+			},
+			gvnErrs:     []error{uniqueErr},
+			expAttempts: 2,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			ctx := context.Background()
+			if tc.gvnDeadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.gvnDeadline)
+				defer cancel()
+			}
+
+			calls := 0
+			fn := func() error {
+				calls++
+				if calls <= len(tc.gvnErrs) {
+					return fmt.Errorf("wrapped: %w", tc.gvnErrs[calls-1])
+				}
+				return nil
+			}
+
+			// When
+			attempts, err := tc.gvnPolicy.do(ctx, fn)
+
+			// Then
+			require.Equal(t, tc.expAttempts, attempts)
+			require.Equal(t, tc.expAttempts, calls)
+			if tc.expErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.True(t, errors.Is(err, tc.expErr))
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_delay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	tcs := map[string]struct {
+		gvnAttempt int
+		expMin     time.Duration
+		expMax     time.Duration
+	}{
+		"success__first_retry":  {gvnAttempt: 1, expMin: 5 * time.Millisecond, expMax: 10 * time.Millisecond},
+		"success__third_retry":  {gvnAttempt: 3, expMin: 20 * time.Millisecond, expMax: 40 * time.Millisecond},
+		"success__capped":       {gvnAttempt: 10, expMin: 25 * time.Millisecond, expMax: 50 * time.Millisecond},
+		"success__no_overflows": {gvnAttempt: 100, expMin: 25 * time.Millisecond, expMax: 50 * time.Millisecond},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			for idx := 0; idx < 20; idx++ {
+				// When
+				delay := policy.delay(tc.gvnAttempt)
+
+				// Then
+				require.GreaterOrEqual(t, delay, tc.expMin)
+				require.LessOrEqual(t, delay, tc.expMax)
+			}
+		})
+	}
+}
+
+func TestBulkInsert_ExecParallel_Retry(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	// Given
+	ctx := context.Background()
+
+	data := make([]*SampleTable, 0, 30)
+	for idx := 0; idx < 30; idx++ {
+		data = append(data, &SampleTable{ID: int64(idx), Col01: fmt.Sprintf("DataRow__%d", idx)})
+	}
+
+	// the batch holding the 15th row deadlocks twice before going through
+	var mutex sync.Mutex
+	deadlocks := 2
+	exec := &fakeExecutor{
+		fail: func(args []interface{}) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			for _, arg := range args {
+				if arg == "DataRow__15" && deadlocks > 0 {
+					deadlocks--
+					return &pqError{Code: pgCodeDeadlockDetected}
+				}
+			}
+			return nil
+		},
+	}
+
+	op, err := NewBulkInsert(data, "sample", []string{"id", "col_01"})
+	require.NoError(t, err)
+	op.BatchSizes = []int{10}
+
+	// When
+	result, err := op.ExecParallel(ctx, SharedConns(exec), ParallelOptions{
+		Concurrency: 3,
+		Retry:       RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+	})
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Retries)
+	require.Equal(t, int64(30), result.RowsAffected)
+	require.Equal(t, 5, exec.calls)
+}
@@ -0,0 +1,102 @@
+package assembler
+
+import (
+	"context"
+	"iter"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+/**
+ * Question: how do the executors living outside of this package run the batches?
+ * Answer: through `ExecWith()`, e.g. `pgxassembler`. The operation still builds the batches, picks the columns to
+ *         return and sends the dead letters, while the `GroupRunner` only runs the statements and reports how each of
+ *         them went with `ExecResult.Succeed()` and `ExecResult.Fail()`. That keeps the drivers out of this package
+ *         altogether: whoever does not use one does not have to pull it in.
+ */
+
+// GroupRunner represents an executor running the batches on its own terms, see `ExecWith()`
+type GroupRunner interface {
+	RunGroups(ctx context.Context, batches iter.Seq2[QueryGroup, error], bind bool) (ExecResult, error)
+}
+
+// ExecWith runs every batch through the runner. When binding, every column the struct has a tag for is returned, see
+//          `ReturningAll`. The rows of the failing batches are sent to `DeadLetters`, if set.
+func (op BulkInsert) ExecWith(ctx context.Context, runner GroupRunner, bind bool) (ExecResult, error) {
+	op.ReturningAll = op.ReturningAll || bind
+	result, err := runner.RunGroups(ctx, op.Batches(), bind)
+	return sendDeadLetters(ctx, op.DeadLetters, result, err)
+}
+
+// ExecWith runs every batch through the runner. Overridden for the same reasons as `Queries()`
+func (op BulkUpsert) ExecWith(ctx context.Context, runner GroupRunner, bind bool) (ExecResult, error) {
+	if err := op.checkBind(bind); err != nil {
+		return ExecResult{}, err
+	}
+
+	op.ReturningAll = op.ReturningAll || bind
+	result, err := runner.RunGroups(ctx, op.Batches(), bind)
+	return sendDeadLetters(ctx, op.DeadLetters, result, err)
+}
+
+// Succeed records a batch that went through along with the number of rows it affected
+func (result *ExecResult) Succeed(group QueryGroup, affected int64) {
+	result.succeed(group, groupOutcome{affected: affected})
+}
+
+// Fail records a batch that did not go through and returns the `BatchError` describing it. Constraint violations are
+//      reported as `ConstraintError`, the same as with the executors of this package.
+func (result *ExecResult) Fail(group QueryGroup, err error) error {
+	return result.fail(group, enrichError(group, err))
+}
+
+// HasHooks reports whether there are hooks to run around the statement of the batch, see `hooks.go`
+func (group QueryGroup) HasHooks() bool {
+	return group.hooks != nil
+}
+
+// BindRows scans the `RETURNING` rows of the batch and writes them back into the data, the same way as `ExecAndBind()`.
+//          Returns the number of rows read. The rows are left for the caller to close.
+func (group QueryGroup) BindRows(rows RowScanner, columns []string) (int64, error) {
+	returned, err := scanRows(rows, columns, group.itemType(), group.tagNames)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writeBack(group.items, returned); err != nil {
+		return 0, err
+	}
+
+	return int64(returned.Len()), nil
+}
+
+// Values goes through the values of every row in the order of `Columns`, extracted the same way as for the batches,
+//        e.g. for `COPY`. Rows needing `DEFAULT` cannot be given as values, and stop it with `ErrCopyDefault`.
+func (op BulkInsert) Values() iter.Seq2[[]interface{}, error] {
+	return func(yield func([]interface{}, error) bool) {
+		fields, err := op.fields()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		width := len(fields)
+
+		for group, err := range op.sqlData() {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if group.defaults > 0 {
+				yield(nil, pkgerrors.WithStack(ErrCopyDefault))
+				return
+			}
+
+			for args := group.Args; len(args) >= width && width > 0; args = args[width:] {
+				if !yield(args[:width:width], nil) {
+					return
+				}
+			}
+		}
+	}
+}
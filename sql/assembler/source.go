@@ -0,0 +1,124 @@
+package assembler
+
+import (
+	"iter"
+	"reflect"
+)
+
+/**
+ * Question: why not just ask for an `iter.Seq` and be done with it?
+ * Answer: `iter.Seq[T]` is generic and the assemblers are not, so we cannot type-assert our way into it without
+ *         knowing `T`. Instead, we inspect the function signatures through reflection, which also lets us accept the
+ *         channels and the pull functions (the `next()` half of `iter.Pull`) that other teams are already using.
+ *
+ *         Streams are read exactly once. Calling `Queries()` or `Batches()` a second time on a stream would find it
+ *         already drained.
+ */
+
+// isStreamType checks if the data type is one of the streaming sources we accept:
+//              - a receivable channel: `chan T` or `<-chan T`
+//              - a push iterator:      `func(yield func(T) bool)` such as `iter.Seq[T]`
+//              - a pull function:      `func() (T, bool)`
+func isStreamType(dataType reflect.Type) bool {
+	switch dataType.Kind() {
+	case reflect.Chan:
+		return dataType.ChanDir()&reflect.RecvDir != 0
+	case reflect.Func:
+		return isSeqType(dataType) || isPullType(dataType)
+	default:
+		return false
+	}
+}
+
+// isSeqType checks if the function type looks like `iter.Seq[T]`
+func isSeqType(dataType reflect.Type) bool {
+	if dataType.NumIn() != 1 || dataType.NumOut() != 0 {
+		return false
+	}
+
+	yieldType := dataType.In(0)
+	return yieldType.Kind() == reflect.Func &&
+		yieldType.NumIn() == 1 &&
+		yieldType.NumOut() == 1 &&
+		yieldType.Out(0).Kind() == reflect.Bool
+}
+
+// isPullType checks if the function type looks like the `next()` function returned by `iter.Pull()`
+func isPullType(dataType reflect.Type) bool {
+	return dataType.NumIn() == 0 &&
+		dataType.NumOut() == 2 &&
+		dataType.Out(1).Kind() == reflect.Bool
+}
+
+// getItemType returns the type of each item held or produced by the data, may it be a slice or a stream
+func getItemType(dataType reflect.Type) reflect.Type {
+	switch {
+	case dataType.Kind() != reflect.Func:
+		return dataType.Elem()
+	case isSeqType(dataType):
+		return dataType.In(0).In(0)
+	default:
+		return dataType.Out(0)
+	}
+}
+
+// getDataItemType returns the type of each item like `getItemType()`, except that slices of `interface{}` go by the
+//                 item they start with. `validateItems()` makes sure that the rest are of the same type.
+func getDataItemType(dataType reflect.Type, dataValue reflect.Value) reflect.Type {
+	itemType := getItemType(dataType)
+	if itemType.Kind() != reflect.Interface || isStreamType(dataType) || dataValue.Len() == 0 {
+		return itemType
+	}
+
+	if item := getItem(dataValue.Index(0)); item.IsValid() {
+		return item.Type()
+	}
+
+	return itemType
+}
+
+// getItem unwraps the item out of its `interface{}`, if it is in one
+func getItem(item reflect.Value) reflect.Value {
+	if item.Kind() == reflect.Interface {
+		return item.Elem()
+	}
+
+	return item
+}
+
+// getItems walks through every item of the data regardless of how it is provided
+func getItems(dataType reflect.Type, dataValue reflect.Value) iter.Seq[reflect.Value] {
+	return func(yield func(reflect.Value) bool) {
+		switch {
+		case dataType.Kind() == reflect.Array || dataType.Kind() == reflect.Slice:
+			for idx := 0; idx < dataValue.Len(); idx++ {
+				if !yield(getItem(dataValue.Index(idx))) {
+					return
+				}
+			}
+
+		case dataType.Kind() == reflect.Chan:
+			for {
+				item, ok := dataValue.Recv()
+				if !ok || !yield(item) {
+					return
+				}
+			}
+
+		case isSeqType(dataType):
+			yieldType := dataType.In(0)
+			yieldFunc := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+				return []reflect.Value{reflect.ValueOf(yield(args[0])).Convert(yieldType.Out(0))}
+			})
+			dataValue.Call([]reflect.Value{yieldFunc})
+
+		default:
+			for {
+				output := dataValue.Call(nil)
+				if !output[1].Bool() || !yield(output[0]) {
+					return
+				}
+			}
+		}
+	}
+}
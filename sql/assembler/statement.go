@@ -0,0 +1,157 @@
+package assembler
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+/**
+ * Question: why keep `QueryGroup.Query` around at all?
+ * Answer: the executors in this package and plenty of callers already go through SQLBoiler, and there is nothing to
+ *         gain by breaking them. `Statement` carries the exact same SQL and arguments for everyone else, and the
+ *         helpers below only need what `database/sql` offers -- so `*sql.DB`, `*sql.Tx`, `*sql.Conn` and `sqlx` all work
+ *         with it as they are.
+ */
+
+// Statement represents the SQL of a batch along with its arguments, ready to be passed to any driver
+type Statement struct {
+	SQL  string
+	Args []interface{}
+
+	// tagNames are the struct tags of the batch the statement was built for, see `TagNames()`
+	tagNames []string
+}
+
+// SQLExecutor represents the parts of `database/sql` that the helpers need
+type SQLExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// ExecContext runs the statement without reading back the `RETURNING` rows
+func (statement Statement) ExecContext(ctx context.Context, db SQLExecutor) (sql.Result, error) {
+	output, err := db.ExecContext(ctx, statement.SQL, statement.Args...)
+	return output, pkgerrors.WithStack(err)
+}
+
+// QueryContext runs the statement and returns the `RETURNING` rows as they are. See `QueryRows()` for scanning them.
+func (statement Statement) QueryContext(ctx context.Context, db SQLExecutor) (*sql.Rows, error) {
+	rows, err := db.QueryContext(ctx, statement.SQL, statement.Args...)
+	return rows, pkgerrors.WithStack(err)
+}
+
+// QueryRows runs the statement and scans the `RETURNING` rows into structs. See `ScanRows()`, except that the columns
+//           are matched with the same tags as the batch the statement was built for.
+func QueryRows[T any](ctx context.Context, db SQLExecutor, statement Statement) ([]T, error) {
+	rows, err := statement.QueryContext(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, pkgerrors.WithStack(err)
+	}
+
+	output, err := scanRows(rows, columns, reflect.TypeFor[T](), statement.tagNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Interface().([]T), nil
+}
+
+// ScanRows reads every row into a new `T`, which is a struct or a pointer to one, matching the columns with the `boil`
+//          tags of the struct. Every column must have a field to go into. `T` may also be a map keyed by column, which
+//          takes every column. The rows are left for the caller to close.
+func ScanRows[T any](rows *sql.Rows) ([]T, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, pkgerrors.WithStack(err)
+	}
+
+	output, err := scanRows(rows, columns, reflect.TypeFor[T](), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Interface().([]T), nil
+}
+
+// RowScanner represents the rows of either `database/sql` or `pgx`
+type RowScanner interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}
+
+// scanRows reads every row into a new item of `itemType`, returned as a slice of them. See `ScanRows()`
+func scanRows(rows RowScanner, columns []string, itemType reflect.Type, tagNames []string) (reflect.Value, error) {
+	if isMapType(itemType) {
+		output := reflect.MakeSlice(reflect.SliceOf(itemType), 0, 0)
+		for rows.Next() {
+			item, err := scanMapRow(rows, columns, itemType)
+			if err != nil {
+				return reflect.Value{}, pkgerrors.WithStack(err)
+			}
+			output = reflect.Append(output, item)
+		}
+
+		return output, pkgerrors.WithStack(rows.Err())
+	}
+
+	structType := itemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	fields, err := getStructFields(structType, columns, tagNames)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if len(fields) != len(columns) {
+		known := make(map[string]bool, len(columns))
+		for _, column := range getStructColumns(structType, tagNames) {
+			known[column] = true
+		}
+
+		missing := make([]string, 0, len(columns))
+		for _, column := range columns {
+			if !known[column] {
+				missing = append(missing, column)
+			}
+		}
+
+		return reflect.Value{}, pkgerrors.Wrapf(ErrScanColumn, "%v", missing)
+	}
+
+	output := reflect.MakeSlice(reflect.SliceOf(itemType), 0, 0)
+	for rows.Next() {
+		item := reflect.New(structType)
+
+		targets := make([]interface{}, 0, len(fields))
+		for _, field := range fields {
+			targets = append(targets, getFieldTarget(item.Elem(), field))
+		}
+
+		if err := rows.Scan(targets...); err != nil {
+			return reflect.Value{}, pkgerrors.WithStack(err)
+		}
+
+		if itemType.Kind() != reflect.Ptr {
+			item = item.Elem()
+		}
+		output = reflect.Append(output, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return reflect.Value{}, pkgerrors.WithStack(err)
+	}
+
+	return output, nil
+}
@@ -0,0 +1,110 @@
+package assembler
+
+import (
+	"context"
+	"testing"
+
+	"code.in.spdigital.sg/sp-digital/athena/db/pg"
+	"code.in.spdigital.sg/sp-digital/athena/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkInsert_Statement(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	// Given
+	data := []SampleTable{
+		{ID: 1, Col01: "DataRow__1"},
+		{ID: 2, Col01: "DataRow__2"},
+		{ID: 3, Col01: "DataRow__3"},
+	}
+
+	op, err := NewBulkInsert(data, "sample_table", []string{"id", "col_01"})
+	require.NoError(t, err)
+	op.BatchSizes = []int{2, 1}
+
+	// When
+	groups, err := op.Queries()
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	for _, group := range groups {
+		require.Equal(t, getFingerprint(group.Statement.SQL), group.Fingerprint)
+		require.Equal(t, group.Args, group.Statement.Args)
+		require.Contains(t, group.Statement.SQL, "INSERT INTO \"sample_table\" (\"id\",\"col_01\")")
+	}
+}
+
+func TestQueryRows(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+		Col02 int    `boil:"col_02"`
+	}
+	type SampleTableIDOnly struct {
+		ID int64 `boil:"id"`
+	}
+	tableName := "sample"
+	createSQL := "" +
+		"CREATE TABLE \"" + tableName + "\" (\n" +
+		"    \"id\" BIGSERIAL PRIMARY KEY,\n" +
+		"    \"col_01\" TEXT,\n" +
+		"    \"col_02\" INTEGER\n" +
+		");"
+
+	t.Run("success__returning_rows", func(t *testing.T) {
+		testutil.WithTxDB(t, func(tx pg.BeginnerExecutor) {
+			// Given
+			ctx := context.Background()
+
+			_, err := tx.ExecContext(ctx, createSQL)
+			require.NoError(t, err)
+
+			data := []*SampleTable{{Col01: "DataRow__1", Col02: 1}, {Col01: "DataRow__2", Col02: 2}}
+			op, err := NewBulkInsertOf(data, tableName, []string{"col_01", "col_02"})
+			require.NoError(t, err)
+
+			batches, err := op.Queries()
+			require.NoError(t, err)
+			require.Len(t, batches, 1)
+
+			// When
+			rows, err := batches[0].QueryRows(ctx, tx)
+
+			// Then
+			require.NoError(t, err)
+			require.Len(t, rows, 2)
+			for idx, row := range rows {
+				require.NotZero(t, row.ID)
+				require.Equal(t, data[idx].Col01, row.Col01)
+				require.Equal(t, data[idx].Col02, row.Col02)
+			}
+		})
+	})
+
+	t.Run("failure__column_not_in_struct", func(t *testing.T) {
+		testutil.WithTxDB(t, func(tx pg.BeginnerExecutor) {
+			// Given
+			ctx := context.Background()
+
+			_, err := tx.ExecContext(ctx, createSQL)
+			require.NoError(t, err)
+
+			op, err := NewBulkInsert([]SampleTable{{ID: 1, Col01: "DataRow__1"}}, tableName, []string{"id", "col_01", "col_02"})
+			require.NoError(t, err)
+
+			groups, err := op.Queries()
+			require.NoError(t, err)
+
+			// When
+			_, err = QueryRows[SampleTableIDOnly](ctx, tx, groups[0].Statement)
+
+			// Then
+			require.ErrorIs(t, err, ErrScanColumn)
+		})
+	})
+}
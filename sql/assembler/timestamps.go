@@ -0,0 +1,128 @@
+package assembler
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"slices"
+	"time"
+)
+
+const (
+	defaultCreatedAtColumn = "created_at"
+	defaultUpdatedAtColumn = "updated_at"
+)
+
+/**
+ * Question: how does this line up with what SQLBoiler does?
+ * Answer: the same way as the generated `Insert()` and `Upsert()`:
+ *             > the creation time is only set when the row has none yet, e.g. a zero `time.Time` or an invalid
+ *               `null.Time`
+ *             > the update time is always set
+ *             > the `ON CONFLICT` branch of an upsert only ever updates the update time, so that the rows keep the time
+ *               they were first created at
+ *
+ *         Unlike SQLBoiler, the times only go into the arguments and are not written into the rows themselves. Use
+ *         `ExecAndBind()` to read them back. Any field whose `driver.Valuer` gives a `time.Time` or NULL will do, which
+ *         covers `time.Time`, `*time.Time`, `null.Time` and `sql.NullTime` alike.
+ */
+
+// Timestamps sets the creation and update times of the rows through `GetCurrentTime()`. Empty column names fall back to
+// `created_at` and `updated_at`.
+type Timestamps struct {
+	CreatedAt string
+	UpdatedAt string
+}
+
+// WithTimestamps returns a copy that sets the creation and update times of the rows. The columns are added to the
+//                insert if the items have them and they are not there yet.
+func (op BulkInsert) WithTimestamps(timestamps Timestamps) BulkInsert {
+	timestamps = timestamps.withDefaults()
+
+	op.Timestamps = &timestamps
+	op.Columns = withColumns(op.Columns, op.timestampColumns())
+
+	return op
+}
+
+// WithTimestamps returns a copy that sets the creation and update times of the rows. Unless the upsert does nothing on
+//                conflict, the update time is added to the columns to update while the creation time is taken out.
+func (op BulkUpsert) WithTimestamps(timestamps Timestamps) BulkUpsert {
+	op.BulkInsert = op.BulkInsert.WithTimestamps(timestamps)
+	if len(op.ColumnsUpdate) == 0 {
+		return op
+	}
+
+	op.ColumnsUpdate = withoutColumns(op.ColumnsUpdate, []string{op.Timestamps.CreatedAt})
+	if slices.Contains(op.timestampColumns(), op.Timestamps.UpdatedAt) {
+		op.ColumnsUpdate = withColumns(op.ColumnsUpdate, []string{op.Timestamps.UpdatedAt})
+	}
+
+	return op
+}
+
+// timestampColumns lists the timestamp columns that the items have. Map rows can take either.
+func (op BulkInsert) timestampColumns() []string {
+	candidates := []string{op.Timestamps.CreatedAt, op.Timestamps.UpdatedAt}
+	if isMapType(op.itemType()) {
+		return candidates
+	}
+
+	known := getStructColumns(op.itemType(), op.TagNames)
+	return withoutColumns(candidates, withoutColumns(candidates, known))
+}
+
+// withDefaults fills in the column names left empty
+func (timestamps Timestamps) withDefaults() Timestamps {
+	if timestamps.CreatedAt == "" {
+		timestamps.CreatedAt = defaultCreatedAtColumn
+	}
+	if timestamps.UpdatedAt == "" {
+		timestamps.UpdatedAt = defaultUpdatedAtColumn
+	}
+
+	return timestamps
+}
+
+// value returns what is sent for the column: `now` for the update time, and for the creation time unless the row has
+//       one already. Other columns are left alone.
+func (timestamps *Timestamps) value(column string, value interface{}, found bool, now time.Time) (interface{}, bool) {
+	if timestamps == nil {
+		return value, found
+	}
+
+	names := timestamps.withDefaults()
+	switch {
+	case column == names.UpdatedAt:
+		return now, true
+	case column == names.CreatedAt && (!found || isZeroTime(value)):
+		return now, true
+	default:
+		return value, found
+	}
+}
+
+// isZeroTime checks if the value holds no time, going through `driver.Valuer` for the likes of `null.Time`
+func isZeroTime(value interface{}) bool {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if reflected := reflect.ValueOf(valuer); reflected.Kind() == reflect.Ptr && reflected.IsNil() {
+			return true
+		}
+
+		output, err := valuer.Value()
+		if err != nil {
+			return false
+		}
+		value = output
+	}
+
+	switch output := value.(type) {
+	case nil:
+		return true
+	case time.Time:
+		return output.IsZero()
+	case *time.Time:
+		return output == nil || output.IsZero()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,172 @@
+package assembler
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+func TestBulkInsert_WithTimestamps(t *testing.T) {
+	type SampleTable struct {
+		ID        int64        `boil:"id"`
+		CreatedAt time.Time    `boil:"created_at"`
+		UpdatedAt sql.NullTime `boil:"updated_at"`
+	}
+
+	type SampleRenamed struct {
+		ID         int64        `boil:"id"`
+		InsertedAt sql.NullTime `boil:"inserted_at"`
+		ModifiedAt *time.Time   `boil:"modified_at"`
+	}
+
+	earlier := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tcs := map[string]struct {
+		gvnData       interface{}
+		gvnColumns    []string
+		gvnTimestamps Timestamps
+		expColumns    []string
+		expCreatedAt  []*time.Time
+	}{
+		"success__struct": {
+			gvnData: []SampleTable{
+				{ID: 1},
+				{ID: 2, CreatedAt: earlier, UpdatedAt: sql.NullTime{Time: earlier, Valid: true}},
+			},
+			gvnColumns:   []string{"id", "created_at", "updated_at"},
+			expColumns:   []string{"id", "created_at", "updated_at"},
+			expCreatedAt: []*time.Time{nil, &earlier},
+		},
+		"success__columns_added": {
+			gvnData:      []SampleTable{{ID: 1}},
+			gvnColumns:   []string{"id"},
+			expColumns:   []string{"id", "created_at", "updated_at"},
+			expCreatedAt: []*time.Time{nil},
+		},
+		"success__renamed": {
+			gvnData: []*SampleRenamed{
+				{ID: 1},
+				{ID: 2, InsertedAt: sql.NullTime{Time: earlier, Valid: true}, ModifiedAt: &earlier},
+			},
+			gvnColumns:    []string{"id"},
+			gvnTimestamps: Timestamps{CreatedAt: "inserted_at", UpdatedAt: "modified_at"},
+			expColumns:    []string{"id", "inserted_at", "modified_at"},
+			expCreatedAt:  []*time.Time{nil, &earlier},
+		},
+		"success__map": {
+			gvnData:      []map[string]interface{}{{"id": 1}, {"id": 2, "created_at": earlier}},
+			gvnColumns:   []string{"id"},
+			expColumns:   []string{"id", "created_at", "updated_at"},
+			expCreatedAt: []*time.Time{nil, &earlier},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			op, err := NewBulkInsert(tc.gvnData, "sample_table", tc.gvnColumns)
+			require.NoError(t, err)
+
+			// When
+			before := GetCurrentTime()
+			op = op.WithTimestamps(tc.gvnTimestamps)
+			groups, err := op.Queries()
+			after := GetCurrentTime()
+
+			// Then
+			require.NoError(t, err)
+			require.Equal(t, tc.expColumns, op.Columns)
+
+			args := groups[0].Args
+			for idx, expCreatedAt := range tc.expCreatedAt {
+				createdAt := args[idx*3+1]
+				if expCreatedAt != nil {
+					require.Equal(t, *expCreatedAt, getTime(t, createdAt))
+				} else {
+					require.WithinRange(t, getTime(t, createdAt), before, after)
+				}
+
+				require.WithinRange(t, getTime(t, args[idx*3+2]), before, after)
+			}
+		})
+	}
+}
+
+func TestBulkUpsert_WithTimestamps(t *testing.T) {
+	type SampleTable struct {
+		ID        int64     `boil:"id"`
+		Col01     string    `boil:"col_01"`
+		CreatedAt time.Time `boil:"created_at"`
+		UpdatedAt time.Time `boil:"updated_at"`
+	}
+
+	tcs := map[string]struct {
+		gvnInsert  boil.Columns
+		gvnUpdate  boil.Columns
+		expInsert  []string
+		expUpdate  []string
+		expSQLPart string
+	}{
+		"success__infer": {
+			gvnInsert:  boil.Infer(),
+			gvnUpdate:  boil.Infer(),
+			expInsert:  []string{"id", "col_01", "created_at", "updated_at"},
+			expUpdate:  []string{"col_01", "updated_at"},
+			expSQLPart: "DO UPDATE SET\n    \"col_01\" = \"excluded\".\"col_01\",\n    \"updated_at\" = \"excluded\".\"updated_at\"\n",
+		},
+		"success__whitelist": {
+			gvnInsert:  boil.Whitelist("id", "col_01"),
+			gvnUpdate:  boil.Whitelist("col_01"),
+			expInsert:  []string{"id", "col_01", "created_at", "updated_at"},
+			expUpdate:  []string{"col_01", "updated_at"},
+			expSQLPart: "DO UPDATE SET\n",
+		},
+		"success__nothing": {
+			gvnInsert:  boil.Infer(),
+			gvnUpdate:  boil.None(),
+			expInsert:  []string{"id", "col_01", "created_at", "updated_at"},
+			expUpdate:  []string{},
+			expSQLPart: "DO NOTHING\n",
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := []SampleTable{{ID: 1, Col01: "one"}}
+
+			op, err := NewBulkUpsertWith(data, "sample_table", []string{"id"}, tc.gvnInsert, tc.gvnUpdate)
+			require.NoError(t, err)
+
+			// When
+			op = op.WithTimestamps(Timestamps{})
+			groups, err := op.Queries()
+
+			// Then
+			require.NoError(t, err)
+			require.Equal(t, tc.expInsert, op.Columns)
+			require.Equal(t, tc.expUpdate, op.ColumnsUpdate)
+			require.Contains(t, groups[0].Statement.SQL, tc.expSQLPart)
+		})
+	}
+}
+
+// getTime reads the time out of the argument sent
+func getTime(t *testing.T, value interface{}) time.Time {
+	t.Helper()
+
+	if valuer, ok := value.(driver.Valuer); ok {
+		raw, err := valuer.Value()
+		require.NoError(t, err)
+		value = raw
+	}
+
+	output, ok := value.(time.Time)
+	require.True(t, ok, "not a time: %#v", value)
+
+	return output
+}
@@ -0,0 +1,321 @@
+package assembler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"iter"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+// TxMode decides how the batches are laid out into transactions
+type TxMode int
+
+const (
+	// TxAllOrNothing runs every batch in one transaction which is rolled back at the first failure. Only the rows of the
+	// failing batch are reported as failed, see `deadletter.go`
+	TxAllOrNothing TxMode = iota
+	// TxPerBatch runs every batch in a transaction of its own, so a failing batch leaves the others committed
+	TxPerBatch
+	// TxSavepoint runs every batch in one transaction, each under a savepoint which is rolled back on failure so the
+	// rest of the batches can carry on before committing
+	TxSavepoint
+)
+
+const savepointName = "sqwole_batch"
+
+// ExecOptions represents how the batches get executed by `ExecTx()`
+type ExecOptions struct {
+	Mode TxMode
+	// Bind writes the `RETURNING` rows back into the data. See `ExecAndBind()`
+	Bind bool
+	// TxOptions are passed as they are when beginning transactions
+	TxOptions *sql.TxOptions
+	// Bisect narrows failing batches down to the rows causing the failure, see `bisect()`. Ignored with
+	// `TxAllOrNothing` since nothing gets committed anyway.
+	Bisect bool
+	// Retry runs batches that failed for transient reasons again. Ignored with `TxAllOrNothing`, see `retry.go`
+	Retry RetryPolicy
+	// DeadLetters receives the rows that could not be written once everything is done, see `deadletter.go`. Defaults to
+	// `BulkInsert.DeadLetters`.
+	DeadLetters DeadLetterSink
+}
+
+// ExecTx runs every batch in transactions laid out according to `opts.Mode`. See `execTx()`
+func (op BulkInsert) ExecTx(ctx context.Context, db boil.ContextBeginner, opts ExecOptions) (ExecResult, error) {
+	op.ReturningAll = op.ReturningAll || opts.Bind
+	if opts.DeadLetters == nil {
+		opts.DeadLetters = op.DeadLetters
+	}
+	return execTx(ctx, db, op.Batches(), op.subgroup, opts)
+}
+
+// ExecTx runs every batch in transactions laid out according to `opts.Mode`. Overridden for the same reasons as
+//        `Queries()`
+func (op BulkUpsert) ExecTx(ctx context.Context, db boil.ContextBeginner, opts ExecOptions) (ExecResult, error) {
+	if err := op.checkBind(opts.Bind); err != nil {
+		return ExecResult{}, err
+	}
+
+	op.ReturningAll = op.ReturningAll || opts.Bind
+	if opts.DeadLetters == nil {
+		opts.DeadLetters = op.DeadLetters
+	}
+	return execTx(ctx, db, op.Batches(), op.subgroup, opts)
+}
+
+// subgroupFunc rebuilds a batch out of the items between `start` and `end` of another batch
+type subgroupFunc func(group QueryGroup, start int, end int) (QueryGroup, error)
+
+// txExecutor holds what is needed to run one assembler's batches inside transactions
+type txExecutor struct {
+	opts     ExecOptions
+	subgroup subgroupFunc
+	result   ExecResult
+}
+
+// execTx runs the batches inside transactions. The result tells which data ranges made it to the database and which
+//        did not. If any batch failed, the error returned is `ErrBatchFailed` (or the `BatchError` itself for
+//        `TxAllOrNothing`); in which case nothing from `TxAllOrNothing` is committed and everything else in the result's
+//        `Succeeded` is. Rows already written back by `opts.Bind` are not reverted when their transaction is rolled
+//        back.
+func execTx(
+	ctx context.Context,
+	db boil.ContextBeginner,
+	batches iter.Seq2[QueryGroup, error],
+	subgroup subgroupFunc,
+	opts ExecOptions,
+) (ExecResult, error) {
+	executor := &txExecutor{
+		opts:     opts,
+		subgroup: subgroup,
+	}
+
+	var (
+		result ExecResult
+		err    error
+	)
+	switch opts.Mode {
+	case TxPerBatch:
+		result, err = executor.execPerBatch(ctx, db, batches)
+	case TxSavepoint:
+		result, err = executor.execSavepoints(ctx, db, batches)
+	default:
+		result, err = executor.execAllOrNothing(ctx, db, batches)
+	}
+
+	return sendDeadLetters(ctx, opts.DeadLetters, result, err)
+}
+
+// execAllOrNothing runs all the batches in one transaction, stopping at the first failure
+func (executor *txExecutor) execAllOrNothing(
+	ctx context.Context,
+	db boil.ContextBeginner,
+	batches iter.Seq2[QueryGroup, error],
+) (ExecResult, error) {
+	result := &executor.result
+
+	tx, err := db.BeginTx(ctx, executor.opts.TxOptions)
+	if err != nil {
+		return *result, pkgerrors.WithStack(err)
+	}
+
+	for group, err := range batches {
+		if err != nil {
+			_ = tx.Rollback()
+			return ExecResult{}, err
+		}
+
+		result.Batches++
+		outcome, err := execGroup(ctx, tx, group, executor.opts.Bind)
+		if err != nil {
+			_ = tx.Rollback()
+
+			// nothing made it in after all
+			result.RowsAffected = 0
+			result.Succeeded = nil
+			result.HookErrors = nil
+			return *result, result.fail(group, err)
+		}
+
+		result.succeed(group, outcome)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ExecResult{}, pkgerrors.WithStack(err)
+	}
+
+	return *result, result.err()
+}
+
+// execSavepoints runs all the batches in one transaction, each under a savepoint
+func (executor *txExecutor) execSavepoints(
+	ctx context.Context,
+	db boil.ContextBeginner,
+	batches iter.Seq2[QueryGroup, error],
+) (ExecResult, error) {
+	result := &executor.result
+
+	tx, err := db.BeginTx(ctx, executor.opts.TxOptions)
+	if err != nil {
+		return *result, pkgerrors.WithStack(err)
+	}
+
+	for group, err := range batches {
+		if err != nil {
+			_ = tx.Rollback()
+			return ExecResult{}, err
+		}
+
+		result.Batches++
+		if err := executor.execGroup(ctx, tx, group); err != nil {
+			_ = tx.Rollback()
+			return ExecResult{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ExecResult{}, pkgerrors.WithStack(err)
+	}
+
+	return *result, result.err()
+}
+
+// execPerBatch runs every batch in a transaction of its own
+func (executor *txExecutor) execPerBatch(
+	ctx context.Context,
+	db boil.ContextBeginner,
+	batches iter.Seq2[QueryGroup, error],
+) (ExecResult, error) {
+	result := &executor.result
+	for group, err := range batches {
+		if err != nil {
+			return *result, err
+		}
+
+		result.Batches++
+
+		// collect the outcome of the batch on the side until we know that the transaction went through
+		var (
+			batchExecutor *txExecutor
+			fatalErr      error
+		)
+		attempts, _ := executor.opts.Retry.do(ctx, func() error {
+			batchExecutor = &txExecutor{opts: executor.opts, subgroup: executor.subgroup}
+			if fatalErr = batchExecutor.execBatchTx(ctx, db, group); fatalErr != nil {
+				return nil
+			}
+
+			// only a batch failing as a whole is run again, partial failures are already narrowed down by bisecting
+			if len(batchExecutor.result.Succeeded) == 0 && len(batchExecutor.result.Failed) > 0 {
+				return batchExecutor.result.Failed[0].Err
+			}
+			return nil
+		})
+		if fatalErr != nil {
+			return *result, fatalErr
+		}
+
+		result.merge(batchExecutor.result, attempts)
+	}
+
+	return *result, result.err()
+}
+
+// execBatchTx runs a single batch in a transaction of its own, committing whatever went through. The outcome of the
+//             batch goes into the result, failing to commit included. Only the errors that should stop everything are
+//             returned.
+func (executor *txExecutor) execBatchTx(
+	ctx context.Context,
+	db boil.ContextBeginner,
+	group QueryGroup,
+) error {
+	result := &executor.result
+
+	tx, err := db.BeginTx(ctx, executor.opts.TxOptions)
+	if err != nil {
+		return pkgerrors.WithStack(err)
+	}
+
+	if err := executor.execGroup(ctx, tx, group); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	// nothing to keep, and the transaction might have been aborted by the failure as well
+	if len(result.Succeeded) == 0 {
+		_ = tx.Rollback()
+		return nil
+	}
+
+	// serialization failures can also show up when committing
+	if err := tx.Commit(); err != nil {
+		*result = ExecResult{}
+		_ = result.fail(group, pkgerrors.WithStack(err))
+	}
+
+	return nil
+}
+
+// execGroup runs a single batch, under a savepoint unless it is in a transaction of its own and there's no need to
+//           go back to before the batch. Narrows the failure down to the rows if asked to. Only
+//           failures of the savepoints themselves are returned since they leave the transaction unusable, everything
+//           else goes into the result.
+func (executor *txExecutor) execGroup(ctx context.Context, tx boil.ContextExecutor, group QueryGroup) error {
+	result := &executor.result
+
+	// only batches under a savepoint can be retried in place, the rest are retried by beginning the transaction over
+	retry := RetryPolicy{}
+	if executor.opts.Mode == TxSavepoint {
+		retry = executor.opts.Retry
+	}
+
+	var outcome groupOutcome
+	attempts, err := retry.do(ctx, func() error {
+		var err error
+		if executor.opts.Mode == TxSavepoint || executor.opts.Bisect {
+			outcome, err = execSavepoint(ctx, tx, group, executor.opts.Bind)
+		} else {
+			outcome, err = execGroup(ctx, tx, group, executor.opts.Bind)
+		}
+		return err
+	})
+	result.Retries += attempts - 1
+
+	switch {
+	case err == nil:
+		result.succeed(group, outcome)
+		return nil
+	case errors.Is(err, errSavepoint):
+		return err
+	case executor.opts.Bisect && !executor.opts.Retry.retries(err):
+		// failures that are going to be retried are left alone, the rows are most likely fine
+		return executor.bisect(ctx, tx, group, err)
+	default:
+		_ = result.failAfter(group, attempts, err)
+		return nil
+	}
+}
+
+// execSavepoint runs a single batch under a savepoint, rolling back to it when the batch fails
+func execSavepoint(ctx context.Context, tx boil.ContextExecutor, group QueryGroup, bind bool) (groupOutcome, error) {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepointName); err != nil {
+		return groupOutcome{}, fmt.Errorf("%w: %w", errSavepoint, err)
+	}
+
+	outcome, err := execGroup(ctx, tx, group, bind)
+	if err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepointName); rbErr != nil {
+			return groupOutcome{}, fmt.Errorf("%w: %w", errSavepoint, rbErr)
+		}
+	}
+
+	if _, relErr := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepointName); relErr != nil {
+		return groupOutcome{}, fmt.Errorf("%w: %w", errSavepoint, relErr)
+	}
+
+	return outcome, err
+}
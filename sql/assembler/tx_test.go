@@ -0,0 +1,185 @@
+package assembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"code.in.spdigital.sg/sp-digital/athena/db/pg"
+	"code.in.spdigital.sg/sp-digital/athena/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkInsert_ExecTx(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+	tableName := "sample"
+	createSQL := "" +
+		"CREATE TABLE \"" + tableName + "\" (\n" +
+		"    \"id\" BIGINT PRIMARY KEY,\n" +
+		"    \"col_01\" TEXT\n" +
+		");"
+
+	tcs := map[string]struct {
+		gvnMode      TxMode
+		expSucceeded []DataRange
+		expCount     int
+	}{
+		"failure__all_or_nothing": {
+			gvnMode:      TxAllOrNothing,
+			expSucceeded: nil,
+			expCount:     0,
+		},
+		"failure__per_batch": {
+			gvnMode:      TxPerBatch,
+			expSucceeded: []DataRange{{Start: 0, End: 4}, {Start: 8, End: 10}},
+			expCount:     6,
+		},
+		"failure__savepoint": {
+			gvnMode:      TxSavepoint,
+			expSucceeded: []DataRange{{Start: 0, End: 4}, {Start: 8, End: 10}},
+			expCount:     6,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			testutil.WithTxDB(t, func(tx pg.BeginnerExecutor) {
+				// Given
+				ctx := context.Background()
+
+				_, err := tx.ExecContext(ctx, createSQL)
+				require.NoError(t, err)
+
+				data := make([]*SampleTable, 0, 10)
+				for idx := 0; idx < 10; idx++ {
+					data = append(data, &SampleTable{ID: int64(idx), Col01: fmt.Sprintf("DataRow__%d", idx)})
+				}
+				// the second batch clashes with itself
+				data[5].ID = data[4].ID
+
+				op, err := NewBulkInsert(data, tableName, []string{"id", "col_01"})
+				require.NoError(t, err)
+				op.BatchSizes = []int{4}
+
+				// When
+				result, err := op.ExecTx(ctx, tx, ExecOptions{Mode: tc.gvnMode})
+
+				// Then
+				var batchErr BatchError
+				require.True(t, errors.As(err, &batchErr) || errors.Is(err, ErrBatchFailed))
+				require.Equal(t, tc.expSucceeded, result.Succeeded)
+				require.Len(t, result.Failed, 1)
+				require.Equal(t, 4, result.Failed[0].DataStart)
+				require.Equal(t, 8, result.Failed[0].DataEnd)
+
+				total := 0
+				err = tx.QueryRowContext(ctx, "SELECT COUNT(1) FROM \""+tableName+"\"").Scan(&total)
+				require.NoError(t, err)
+				require.Equal(t, tc.expCount, total)
+			})
+		})
+	}
+}
+
+func TestBulkInsert_ExecTx_Bisect(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+	tableName := "sample"
+	createSQL := "" +
+		"CREATE TABLE \"" + tableName + "\" (\n" +
+		"    \"id\" BIGINT PRIMARY KEY,\n" +
+		"    \"col_01\" TEXT NOT NULL CHECK (\"col_01\" <> '')\n" +
+		");"
+
+	tcs := map[string]struct {
+		gvnMode      TxMode
+		gvnBadRows   []int
+		gvnRetry     RetryPolicy
+		expRowErrors []int
+		expCount     int
+	}{
+		"success__no_bad_rows": {
+			gvnMode:      TxSavepoint,
+			gvnBadRows:   nil,
+			expRowErrors: nil,
+			expCount:     10,
+		},
+		"failure__savepoint_bad_rows": {
+			gvnMode:      TxSavepoint,
+			gvnBadRows:   []int{5, 6},
+			expRowErrors: []int{5, 6},
+			expCount:     8,
+		},
+		"failure__per_batch_bad_rows": {
+			gvnMode:      TxPerBatch,
+			gvnBadRows:   []int{0, 9},
+			expRowErrors: []int{0, 9},
+			expCount:     8,
+		},
+		"failure__retryable_without_retries": {
+			gvnMode:      TxPerBatch,
+			gvnBadRows:   []int{5, 6},
+			gvnRetry:     RetryPolicy{Retryable: func(error) bool { return true }},
+			expRowErrors: []int{5, 6},
+			expCount:     8,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			testutil.WithTxDB(t, func(tx pg.BeginnerExecutor) {
+				// Given
+				ctx := context.Background()
+
+				_, err := tx.ExecContext(ctx, createSQL)
+				require.NoError(t, err)
+
+				data := make([]*SampleTable, 0, 10)
+				for idx := 0; idx < 10; idx++ {
+					data = append(data, &SampleTable{ID: int64(idx), Col01: fmt.Sprintf("DataRow__%d", idx)})
+				}
+				for _, idx := range tc.gvnBadRows {
+					data[idx].Col01 = ""
+				}
+
+				op, err := NewBulkInsert(data, tableName, []string{"id", "col_01"})
+				require.NoError(t, err)
+				op.BatchSizes = []int{4}
+
+				// When
+				result, err := op.ExecTx(ctx, tx, ExecOptions{
+					Mode:   tc.gvnMode,
+					Bisect: true,
+					Retry:  tc.gvnRetry,
+				})
+
+				// Then
+				require.Empty(t, result.Failed)
+				if tc.expRowErrors == nil {
+					require.NoError(t, err)
+				} else {
+					require.True(t, errors.Is(err, ErrBatchFailed))
+				}
+
+				indices := make([]int, 0, len(result.RowErrors))
+				for _, rowErr := range result.RowErrors {
+					require.Same(t, data[rowErr.DataIndex], rowErr.Row)
+					indices = append(indices, rowErr.DataIndex)
+				}
+				require.ElementsMatch(t, tc.expRowErrors, indices)
+				require.Equal(t, int64(tc.expCount), result.RowsAffected)
+
+				total := 0
+				err = tx.QueryRowContext(ctx, "SELECT COUNT(1) FROM \""+tableName+"\"").Scan(&total)
+				require.NoError(t, err)
+				require.Equal(t, tc.expCount, total)
+			})
+		})
+	}
+}
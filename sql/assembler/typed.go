@@ -0,0 +1,150 @@
+package assembler
+
+import (
+	"context"
+	"iter"
+
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+/**
+ * Question: why is `T` not constrained to structs?
+ * Answer: Go's type parameters cannot express "any struct" nor "any pointer to a struct", so `T` is still checked once
+ *         through reflection when constructing. What the compiler does catch is handing over something that is not a
+ *         slice at all, and every batch coming out of these is already `[]T` so nobody needs to type-assert.
+ *
+ *         In other words, `NewBulkInsertOf[int]()` compiles just fine and fails at runtime with `ErrDataNotStruct`,
+ *         the same as `NewBulkInsert()` would.
+ *
+ * Question: why `Items` and not `Data`?
+ * Answer: `BulkInsert` already has a `Data` field. Naming ours the same would shadow it, leaving the untyped one to
+ *         whichever code goes through the embedded struct.
+ */
+
+// BatchOf represents a batch from the typed assemblers together with the data it was built from
+type BatchOf[T any] struct {
+	QueryGroup
+	Data []T
+}
+
+// Bind runs the batch's query and returns the rows from the `RETURNING` clause
+func (batch BatchOf[T]) Bind(ctx context.Context, exec boil.Executor) ([]T, error) {
+	output := make([]T, 0, len(batch.Data))
+	if err := batch.Query.Bind(ctx, exec, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// QueryRows runs the batch's statement through `database/sql` and returns the rows from the `RETURNING` clause
+func (batch BatchOf[T]) QueryRows(ctx context.Context, db SQLExecutor) ([]T, error) {
+	return QueryRows[T](ctx, db, batch.Statement)
+}
+
+// BulkInsertOf represents a type-safe assembler for bulk insert SQL. `Items` is the same slice as `BulkInsert.Data`.
+type BulkInsertOf[T any] struct {
+	BulkInsert
+	Items []T
+}
+
+// Queries returns all the typed batches. See `BulkInsert.Queries()`
+func (op BulkInsertOf[T]) Queries() ([]BatchOf[T], error) {
+	return collectBatches(op.Batches())
+}
+
+// Batches returns an iterator of the typed batches. See `BulkInsert.Batches()`
+func (op BulkInsertOf[T]) Batches() iter.Seq2[BatchOf[T], error] {
+	return withData(op.BulkInsert.Batches(), op.Items)
+}
+
+// BulkUpsertOf represents a type-safe assembler for bulk upsert SQL. `Items` is the same slice as `BulkInsert.Data`.
+type BulkUpsertOf[T any] struct {
+	BulkUpsert
+	Items []T
+}
+
+// Queries returns all the typed batches. See `BulkUpsert.Queries()`
+func (op BulkUpsertOf[T]) Queries() ([]BatchOf[T], error) {
+	return collectBatches(op.Batches())
+}
+
+// Batches returns an iterator of the typed batches. See `BulkUpsert.Batches()`
+func (op BulkUpsertOf[T]) Batches() iter.Seq2[BatchOf[T], error] {
+	return withData(op.BulkUpsert.Batches(), op.Items)
+}
+
+// NewBulkInsertOf creates a new instance that will help assemble a bulk INSERT SQL for Postgres out of a typed slice.
+//                 `T` is only checked at runtime, see the top of the file.
+func NewBulkInsertOf[T any](
+	data []T,
+	table string,
+	columns []string,
+	opts ...Option,
+) (BulkInsertOf[T], error) {
+	op, err := NewBulkInsert(data, table, columns, opts...)
+	if err != nil {
+		return BulkInsertOf[T]{}, err
+	}
+
+	return BulkInsertOf[T]{
+		BulkInsert: op,
+		Items:      data,
+	}, nil
+}
+
+// NewBulkUpsertOf creates a new instance that will help assemble a bulk INSERT ON CONFLICT SQL for Postgres out of a
+//                 typed slice. `T` is only checked at runtime, see the top of the file.
+func NewBulkUpsertOf[T any](
+	data []T,
+	table string,
+	conflicts []string,
+	columnsInsert []string,
+	columnsUpdate []string,
+	opts ...Option,
+) (BulkUpsertOf[T], error) {
+	op, err := NewBulkUpsert(data, table, conflicts, columnsInsert, columnsUpdate, opts...)
+	if err != nil {
+		return BulkUpsertOf[T]{}, err
+	}
+
+	return BulkUpsertOf[T]{
+		BulkUpsert: op,
+		Items:      data,
+	}, nil
+}
+
+// collectBatches drains the typed batch iterator into a slice, stopping at the first error
+func collectBatches[T any](batches iter.Seq2[BatchOf[T], error]) ([]BatchOf[T], error) {
+	output := make([]BatchOf[T], 0)
+	for batch, err := range batches {
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, batch)
+	}
+
+	return output, nil
+}
+
+// withData pairs every batch with the data it covers. Sorted batches get a copy of their rows in the order sent.
+func withData[T any](batches iter.Seq2[QueryGroup, error], data []T) iter.Seq2[BatchOf[T], error] {
+	return func(yield func(BatchOf[T], error) bool) {
+		for group, err := range batches {
+			batch := BatchOf[T]{QueryGroup: group}
+			if err == nil {
+				batch.Data = data[group.DataStart:group.DataEnd]
+				if group.DataIndices != nil {
+					batch.Data = make([]T, 0, len(group.DataIndices))
+					for _, idx := range group.DataIndices {
+						batch.Data = append(batch.Data, data[idx])
+					}
+				}
+			}
+
+			if !yield(batch, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package assembler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkInsertOf_Batches(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+
+	tcs := map[string]struct {
+		gvnRowsCount  int
+		gvnBatchSizes []int
+		expLengths    []int
+	}{
+		"success__single_batch": {
+			gvnRowsCount:  3,
+			gvnBatchSizes: nil,
+			expLengths:    []int{3},
+		},
+		"success__multiple_batches": {
+			gvnRowsCount:  7,
+			gvnBatchSizes: []int{4, 2, 1},
+			expLengths:    []int{4, 2, 1},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			data := make([]*SampleTable, 0, tc.gvnRowsCount)
+			for idx := 0; idx < tc.gvnRowsCount; idx++ {
+				data = append(data, &SampleTable{ID: int64(idx), Col01: fmt.Sprintf("DataRow__%d", idx)})
+			}
+
+			op, err := NewBulkInsertOf(data, "sample", []string{"id", "col_01"})
+			require.NoError(t, err)
+			op.BatchSizes = tc.gvnBatchSizes
+
+			// When
+			batches, err := op.Queries()
+
+			// Then
+			require.NoError(t, err)
+			require.Len(t, batches, len(tc.expLengths))
+			for idx, batch := range batches {
+				require.Len(t, batch.Data, tc.expLengths[idx])
+				require.Same(t, data[batch.DataStart], batch.Data[0])
+			}
+		})
+	}
+}
+
+func TestNewBulkUpsertOf(t *testing.T) {
+	type SampleTable struct {
+		ID int64 `boil:"id"`
+	}
+
+	tcs := map[string]struct {
+		gvnData []SampleTable
+		expErr  error
+	}{
+		"success__nonempty_slice": {
+			gvnData: []SampleTable{{ID: 1}},
+		},
+		"failure__empty_slice": {
+			gvnData: []SampleTable{},
+			expErr:  ErrDataEmpty,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// When
+			op, err := NewBulkUpsertOf(tc.gvnData, "sample", []string{"id"}, []string{"id"}, nil)
+
+			// Then
+			if tc.expErr != nil {
+				require.EqualError(t, err, tc.expErr.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.gvnData, op.Items)
+			require.Equal(t, []string{"id"}, op.ColumnsUpdate)
+		})
+	}
+}
+
+func TestNewBulkInsertOf_NotStruct(t *testing.T) {
+	// When
+	_, err := NewBulkInsertOf([]int{1, 2}, "sample", nil)
+
+	// Then
+	require.ErrorIs(t, err, ErrDataNotStruct)
+}
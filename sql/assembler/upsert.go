@@ -2,9 +2,10 @@ package assembler
 
 import (
 	"fmt"
+	"iter"
 	"strings"
 
-	"github.com/volatiletech/sqlboiler/v4/queries"
+	pkgerrors "github.com/pkg/errors"
 )
 
 // BulkUpsert represents an assembler for bulk upsert SQL
@@ -17,20 +18,46 @@ type BulkUpsert struct {
 // Queries returns the built SQL statement as a SQLBoiler `queries.Query` object. Overridden because our `call to `sqlStatement()`
 //         is overridden, and we want to call `BulkUpsert`'s rather than `BulkInsert`'s
 func (op BulkUpsert) Queries() ([]QueryGroup, error) {
-	groups, err := op.sqlData()
-	if err != nil {
-		return nil, err
+	return collectGroups(op.Batches())
+}
+
+// Batches returns an iterator that builds each batch only when it is asked for. Overridden for the same reasons as
+//         `Queries()`. Columns both sent as `DEFAULT` and in `ColumnsUpdate` are rejected, see
+//         `WithDefaultWhenZero()`, and so are map rows missing their keys with `MissingKeyDefault`, see `sqlGroup()`.
+func (op BulkUpsert) Batches() iter.Seq2[QueryGroup, error] {
+	op.updated = op.ColumnsUpdate
+	batches := withHooks(withStatement(op.sqlData(), op.sqlStatement), op.groupHooks(true, op.subgroup))
+
+	return func(yield func(QueryGroup, error) bool) {
+		defaults := op.defaultColumns()
+		updated := withoutColumns(defaults, withoutColumns(defaults, op.ColumnsUpdate))
+		if len(updated) > 0 {
+			yield(QueryGroup{}, pkgerrors.Wrapf(ErrColumnInvalid, "DEFAULT when zero for columns updated %q", updated))
+			return
+		}
+
+		batches(yield)
 	}
+}
 
-	for idx := range groups {
-		group := &groups[idx]
-		group.Query = queries.Raw(
-			op.sqlStatement(*group),
-			group.Args...,
-		)
+// WithDefaultWhenZero returns a copy that sends `DEFAULT` for these columns when they are zero or NULL, and takes them
+//                     out of the columns to update. The update goes through `excluded`, which holds the default: a
+//                     conflicting row would otherwise have its serial ID replaced by the next one in the sequence.
+func (op BulkUpsert) WithDefaultWhenZero(columns ...string) BulkUpsert {
+	op.BulkInsert = op.BulkInsert.WithDefaultWhenZero(columns...)
+	op.ColumnsUpdate = withoutColumns(op.ColumnsUpdate, columns)
+
+	return op
+}
+
+// checkBind turns down binding the `RETURNING` rows of `DO NOTHING`, which leaves out the rows that were already there.
+//           There would be no telling which of the items the remaining rows belong to.
+func (op BulkUpsert) checkBind(bind bool) error {
+	if bind && len(op.ColumnsUpdate) == 0 {
+		return pkgerrors.WithStack(ErrBindDoNothing)
 	}
 
-	return groups, nil
+	return nil
 }
 
 // SQL builds the raw SQL and the corresponding arguments that can be easily passed to SQLBoiler's APIs
@@ -44,14 +71,19 @@ func (op BulkUpsert) sqlStatement(group QueryGroup) string {
 	colsConflict := strings.Join(quoteNames(op.ConflictTargets), ",")
 	cols := strings.Join(quoteNames(op.Columns), ",")
 	rows := strings.Join(group.Rows, ",\n")
+	action := "DO UPDATE SET\n" + colsUpdate + "\n"
+	if len(op.ColumnsUpdate) == 0 {
+		// nothing to update, see `boil.None()` in `columns.go`
+		action = "DO NOTHING\n"
+	}
+
 	sql := "" +
 		"INSERT INTO \"" + op.Table + "\" (" + cols + ")\n" +
 		"VALUES\n" +
 		rows + "\n" +
 		"ON CONFLICT (" + colsConflict + ")\n" +
-		"DO UPDATE SET\n" +
-		colsUpdate + "\n" +
-		"RETURNING (" + cols + ")"
+		action +
+		"RETURNING " + strings.Join(quoteNames(op.returningColumns()), ",")
 
 	return sql
 }
@@ -0,0 +1,463 @@
+package assembler
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+/**
+ * Question: why a single goroutine doing the writes?
+ * Answer: the rows come in from everywhere, but the batches are better off going out one at a time -- they are already
+ *         as large as Postgres allows, and a second flush running alongside would only fight the first one for locks.
+ *         While a flush is running, new rows pile up in the buffer until it is full, at which point `Write()` blocks.
+ *         That is the backpressure: producers slow down to the pace the database can keep up with.
+ *
+ * Question: what about two upserts of the same row landing in the same flush?
+ * Answer: Postgres refuses to update a row twice in one statement, and would fail the whole flush over it. The rows of
+ *         a flush sharing their conflict targets are collapsed into the last of them instead, which takes the place of
+ *         the first. The rows it replaced are settled with its outcome, as if they had been written and overwritten
+ *         right away. Rows with NULL in any of the conflict targets never conflict, and are left alone.
+ *
+ * Question: why not hold a lock while writing?
+ * Answer: a write waiting for room in the buffer would keep `Close()` waiting as well, for as long as the flush at hand
+ *         takes. Writes are only counted instead, and give up with `ErrWriterClosed` once the writer is closing.
+ */
+
+const (
+	defaultWriterMaxRows  = 1000
+	defaultWriterMaxDelay = time.Second
+)
+
+// WriterOptions represents when and how a `Writer` flushes the rows it was given
+type WriterOptions struct {
+	// MaxRows flushes as soon as this many rows are waiting. Defaults to 1000.
+	MaxRows int
+	// MaxDelay flushes rows that have been waiting this long, even if there are not enough of them. Defaults to 1s.
+	MaxDelay time.Duration
+	// BufferSize is the most rows waiting for the next flush before `Write()` blocks. Defaults to twice `MaxRows`.
+	BufferSize int
+	// SortBy sends the rows of each flush ordered by these columns. See `BulkInsert.SortBy`
+	SortBy []string
+	// Timestamps sets the creation and update times of the rows of each flush when set. See `BulkInsert.WithTimestamps`
+	Timestamps *Timestamps
+	// Hooks run around the statement of every flush when set, see `hooks.go`
+	Hooks *Hooks
+	// TagNames are the struct tags the columns are read from, `boil` when empty. See `TagNames()`
+	TagNames []string
+	// Exec is how every flush is executed. Use `TxPerBatch` or `Bisect` to keep bad rows from failing the others.
+	Exec ExecOptions
+}
+
+// WriteFuture represents the outcome of a single row given to a `Writer`, available once the row has been flushed
+type WriteFuture struct {
+	done chan struct{}
+	err  error
+}
+
+// Done is closed once the row has been flushed, successfully or not
+func (future *WriteFuture) Done() <-chan struct{} {
+	return future.done
+}
+
+// Err returns why the row could not be written, if it has been flushed and failed. See `Wait()` for blocking.
+func (future *WriteFuture) Err() error {
+	select {
+	case <-future.done:
+		return future.err
+	default:
+		return nil
+	}
+}
+
+// Wait blocks until the row has been flushed and returns why it could not be written, if it failed
+func (future *WriteFuture) Wait(ctx context.Context) error {
+	select {
+	case <-future.done:
+		return future.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// settle records the outcome of the row
+func (future *WriteFuture) settle(err error) {
+	future.err = err
+	close(future.done)
+}
+
+// Writer represents a buffer of rows that are written to the database in bulk, in the background. It is safe to use
+//        from multiple goroutines.
+type Writer[T any] struct {
+	ctx   context.Context
+	db    boil.ContextBeginner
+	opts  WriterOptions
+	build func(data []T) (writerOp, error)
+	// key tells the rows conflicting with each other apart, nil when they never do. See `collapse()`
+	key func(row T) (string, bool)
+
+	incoming chan writerRow[T]
+	flushes  chan chan struct{}
+	done     chan struct{}
+
+	// closing is closed by `Close()`, then `idle` once none of the writes are left
+	closing chan struct{}
+	idle    chan struct{}
+	mutex   sync.Mutex
+	writing int
+	closed  bool
+}
+
+// writerRow represents a row waiting to be flushed along with the future to report back on
+type writerRow[T any] struct {
+	row    T
+	future *WriteFuture
+}
+
+// writerOp is what a `Writer` needs out of `BulkInsert` and `BulkUpsert`
+type writerOp interface {
+	ExecTx(ctx context.Context, db boil.ContextBeginner, opts ExecOptions) (ExecResult, error)
+}
+
+// NewWriter creates a new instance that inserts the rows written to it in bulk. The background flushes run with `ctx`,
+//           which should outlive the writer.
+func NewWriter[T any](
+	ctx context.Context,
+	db boil.ContextBeginner,
+	table string,
+	columns []string,
+	opts WriterOptions,
+) (*Writer[T], error) {
+	return newWriter(ctx, db, opts, nil, func(data []T) (writerOp, error) {
+		op, err := NewBulkInsert(data, table, columns, TagNames(opts.TagNames...))
+		op.SortBy = opts.SortBy
+		op.Hooks = opts.Hooks
+		if opts.Timestamps != nil && err == nil {
+			op = op.WithTimestamps(*opts.Timestamps)
+		}
+		return op, err
+	})
+}
+
+// NewUpsertWriter creates a new instance that upserts the rows written to it in bulk. The rows of a flush sharing their
+//                 conflict targets are collapsed into the last of them, see the top of the file. See `NewWriter()`
+func NewUpsertWriter[T any](
+	ctx context.Context,
+	db boil.ContextBeginner,
+	table string,
+	conflicts []string,
+	columnsInsert []string,
+	columnsUpdate []string,
+	opts WriterOptions,
+) (*Writer[T], error) {
+	return newWriter(ctx, db, opts, getConflictKey[T](conflicts, opts.TagNames), func(data []T) (writerOp, error) {
+		op, err := NewBulkUpsert(data, table, conflicts, columnsInsert, columnsUpdate, TagNames(opts.TagNames...))
+		op.SortBy = opts.SortBy
+		op.Hooks = opts.Hooks
+		if opts.Timestamps != nil && err == nil {
+			op = op.WithTimestamps(*opts.Timestamps)
+		}
+		return op, err
+	})
+}
+
+// newWriter fills in the defaults, checks that `T` can be written at all, and starts the background goroutine
+func newWriter[T any](
+	ctx context.Context,
+	db boil.ContextBeginner,
+	opts WriterOptions,
+	key func(row T) (string, bool),
+	build func(data []T) (writerOp, error),
+) (*Writer[T], error) {
+	itemType := reflect.TypeFor[T]()
+	if itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+	if itemType.Kind() != reflect.Struct && !isMapType(reflect.TypeFor[T]()) {
+		return nil, pkgerrors.WithStack(ErrDataNotStruct)
+	}
+
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = defaultWriterMaxRows
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = defaultWriterMaxDelay
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 2 * opts.MaxRows
+	}
+
+	writer := &Writer[T]{
+		ctx:      ctx,
+		db:       db,
+		opts:     opts,
+		build:    build,
+		key:      key,
+		incoming: make(chan writerRow[T], opts.BufferSize),
+		flushes:  make(chan chan struct{}),
+		done:     make(chan struct{}),
+		closing:  make(chan struct{}),
+		idle:     make(chan struct{}),
+	}
+	go writer.run()
+
+	return writer, nil
+}
+
+// Write queues the row for the next flush. Blocks while the buffer is full, until there is room, `ctx` is done or the
+//       writer is closed.
+func (writer *Writer[T]) Write(ctx context.Context, row T) (*WriteFuture, error) {
+	if !writer.enter() {
+		return nil, ErrWriterClosed
+	}
+	defer writer.leave()
+
+	future := &WriteFuture{done: make(chan struct{})}
+	select {
+	case writer.incoming <- writerRow[T]{row: row, future: future}:
+		return future, nil
+	case <-writer.closing:
+		return nil, ErrWriterClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// enter counts the write in, unless the writer is closed
+func (writer *Writer[T]) enter() bool {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.closed {
+		return false
+	}
+
+	writer.writing++
+	return true
+}
+
+// leave counts the write out, and lets `run()` know when it was the last one since the writer was closed
+func (writer *Writer[T]) leave() {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	writer.writing--
+	if writer.closed && writer.writing == 0 {
+		close(writer.idle)
+	}
+}
+
+// Flush writes every row queued before the call and waits for it to finish. The outcome of each row is reported
+//       through its future. If `ctx` ends first, the flush still goes on in the background.
+func (writer *Writer[T]) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case writer.flushes <- flushed:
+	case <-writer.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting rows, writes whatever is left, and waits for it to finish. Writes still waiting for room in the
+//       buffer give up with `ErrWriterClosed`. If `ctx` ends first, the remaining rows are still written in the
+//       background.
+func (writer *Writer[T]) Close(ctx context.Context) error {
+	writer.mutex.Lock()
+	if !writer.closed {
+		writer.closed = true
+		close(writer.closing)
+		if writer.writing == 0 {
+			close(writer.idle)
+		}
+	}
+	writer.mutex.Unlock()
+
+	select {
+	case <-writer.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run collects the incoming rows and flushes them when there are enough, when the oldest has waited long enough, when
+//     asked to, or when the writer is closed
+func (writer *Writer[T]) run() {
+	defer close(writer.done)
+
+	pending := make([]writerRow[T], 0, writer.opts.MaxRows)
+	timer := time.NewTimer(writer.opts.MaxDelay)
+	timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		if len(pending) > 0 {
+			writer.write(pending)
+			pending = make([]writerRow[T], 0, writer.opts.MaxRows)
+		}
+	}
+
+	add := func(row writerRow[T]) {
+		pending = append(pending, row)
+		if len(pending) == 1 {
+			timer.Reset(writer.opts.MaxDelay)
+		}
+		if len(pending) >= writer.opts.MaxRows {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case row := <-writer.incoming:
+			add(row)
+
+		case <-writer.closing:
+			// the writes still going may get their rows in until the last of them is gone
+			for idle := writer.idle; idle != nil; {
+				select {
+				case row := <-writer.incoming:
+					add(row)
+				case <-idle:
+					idle = nil
+				}
+			}
+			for len(writer.incoming) > 0 {
+				add(<-writer.incoming)
+			}
+			flush()
+			return
+
+		case <-timer.C:
+			flush()
+
+		case flushed := <-writer.flushes:
+			// everything queued before `Flush()` was called is already in the channel
+			for count := len(writer.incoming); count > 0; count-- {
+				add(<-writer.incoming)
+			}
+			flush()
+			close(flushed)
+		}
+	}
+}
+
+// write executes a single flush and settles the future of every row in it
+func (writer *Writer[T]) write(pending []writerRow[T]) {
+	data, positions := writer.collapse(pending)
+
+	var result ExecResult
+	op, err := writer.build(data)
+	if err == nil {
+		result, err = op.ExecTx(writer.ctx, writer.db, writer.opts.Exec)
+	}
+
+	errs := getRowErrors(len(data), result, err)
+	for idx, row := range pending {
+		row.future.settle(errs[positions[idx]])
+	}
+}
+
+// collapse keeps the last of the rows sharing a key in place of the first of them, see the top of the file. Returns
+//          the rows to write, along with where each of the pending rows ended up among them.
+func (writer *Writer[T]) collapse(pending []writerRow[T]) ([]T, []int) {
+	data := make([]T, 0, len(pending))
+	positions := make([]int, 0, len(pending))
+	keys := make(map[string]int)
+
+	for _, row := range pending {
+		if writer.key != nil {
+			if key, ok := writer.key(row.row); ok {
+				if position, found := keys[key]; found {
+					data[position] = row.row
+					positions = append(positions, position)
+					continue
+				}
+				keys[key] = len(data)
+			}
+		}
+
+		positions = append(positions, len(data))
+		data = append(data, row.row)
+	}
+
+	return data, positions
+}
+
+// getConflictKey returns how to tell the rows of `T` apart by their values for the conflict targets, formatted the
+//                same way as for `ConstraintError`. Rows with NULL in any of them have no key. Returns nil when the
+//                conflict targets are not all on the struct, which the constructor reports anyway.
+func getConflictKey[T any](conflicts []string, tagNames []string) func(row T) (string, bool) {
+	fields, err := getStructFields(reflect.TypeFor[T](), conflicts, tagNames)
+	if err != nil || len(fields) != len(conflicts) || len(fields) == 0 {
+		return nil
+	}
+
+	return func(row T) (string, bool) {
+		item := reflect.ValueOf(row)
+		if item.Kind() == reflect.Ptr {
+			if item.IsNil() {
+				return "", false
+			}
+			item = item.Elem()
+		}
+
+		values := make([]string, 0, len(fields))
+		for _, field := range fields {
+			value, ok := formatValue(getFieldValue(item, field))
+			if !ok {
+				return "", false
+			}
+			values = append(values, value)
+		}
+
+		return strings.Join(values, "\x00"), true
+	}
+}
+
+// getRowErrors works out why each row of the data failed, if it did, from the outcome of an execution. Rows that were
+//              never attempted get the error the execution stopped with.
+func getRowErrors(count int, result ExecResult, err error) []error {
+	errs := make([]error, count)
+	settled := make([]bool, count)
+
+	for _, succeeded := range result.Succeeded {
+		for _, idx := range succeeded.Indices() {
+			settled[idx] = true
+		}
+	}
+
+	for _, batchErr := range result.Failed {
+		for _, idx := range batchErr.Indices() {
+			errs[idx] = batchErr
+			settled[idx] = true
+		}
+	}
+
+	for _, rowErr := range result.RowErrors {
+		errs[rowErr.DataIndex] = rowErr
+		settled[rowErr.DataIndex] = true
+	}
+
+	for idx := range errs {
+		if !settled[idx] {
+			errs[idx] = err
+		}
+	}
+
+	return errs
+}
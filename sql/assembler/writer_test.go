@@ -0,0 +1,336 @@
+package assembler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"code.in.spdigital.sg/sp-digital/athena/db/pg"
+	"code.in.spdigital.sg/sp-digital/athena/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+func TestWriter_getRowErrors(t *testing.T) {
+	errBatch := BatchError{Batch: 1, DataStart: 2, DataEnd: 4, Attempts: 1}
+	errSorted := BatchError{Batch: 1, DataStart: 2, DataEnd: 4, DataIndices: []int{2, 1}, Attempts: 1}
+	errRow := RowError{Batch: 0, DataIndex: 1}
+	errStopped := errors.New("stopped")
+
+	tcs := map[string]struct {
+		gvnResult ExecResult
+		gvnErr    error
+		expErrs   []error
+	}{
+		"success__all_rows": {
+			gvnResult: ExecResult{Succeeded: []DataRange{{Start: 0, End: 2}, {Start: 2, End: 5}}},
+			gvnErr:    nil,
+			expErrs:   []error{nil, nil, nil, nil, nil},
+		},
+		"failure__batch_and_row": {
+			gvnResult: ExecResult{
+				Succeeded: []DataRange{{Start: 0, End: 1}, {Start: 4, End: 5}},
+				Failed:    []BatchError{errBatch},
+				RowErrors: []RowError{errRow},
+			},
+			gvnErr:  ErrBatchFailed,
+			expErrs: []error{nil, errRow, errBatch, errBatch, nil},
+		},
+		"failure__sorted_batch": {
+			gvnResult: ExecResult{
+				Succeeded: []DataRange{
+					{Start: 0, End: 2, DataIndices: []int{4, 3}},
+					{Start: 4, End: 5, DataIndices: []int{0}},
+				},
+				Failed: []BatchError{errSorted},
+			},
+			gvnErr:  ErrBatchFailed,
+			expErrs: []error{nil, errSorted, errSorted, nil, nil},
+		},
+		"failure__never_attempted": {
+			gvnResult: ExecResult{Succeeded: []DataRange{{Start: 0, End: 2}}},
+			gvnErr:    errStopped,
+			expErrs:   []error{nil, nil, errStopped, errStopped, errStopped},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// When
+			errs := getRowErrors(5, tc.gvnResult, tc.gvnErr)
+
+			// Then
+			require.Equal(t, tc.expErrs, errs)
+		})
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	type SampleTable struct {
+		ID int64 `boil:"id"`
+	}
+
+	t.Run("success__pointers", func(t *testing.T) {
+		// When
+		writer, err := NewWriter[*SampleTable](context.Background(), nil, "sample", []string{"id"}, WriterOptions{})
+
+		// Then
+		require.NoError(t, err)
+		require.NoError(t, writer.Close(context.Background()))
+
+		_, err = writer.Write(context.Background(), &SampleTable{})
+		require.ErrorIs(t, err, ErrWriterClosed)
+	})
+
+	t.Run("failure__not_struct", func(t *testing.T) {
+		// When
+		_, err := NewWriter[int](context.Background(), nil, "sample", []string{"id"}, WriterOptions{})
+
+		// Then
+		require.ErrorIs(t, err, ErrDataNotStruct)
+	})
+}
+
+func TestWriter_Write(t *testing.T) {
+	type SampleTable struct {
+		ID    int64  `boil:"id"`
+		Col01 string `boil:"col_01"`
+	}
+	tableName := "sample"
+	createSQL := "" +
+		"CREATE TABLE \"" + tableName + "\" (\n" +
+		"    \"id\" BIGINT PRIMARY KEY,\n" +
+		"    \"col_01\" TEXT NOT NULL CHECK (\"col_01\" <> '')\n" +
+		");"
+
+	tcs := map[string]struct {
+		gvnOpts    WriterOptions
+		gvnFlush   bool
+		gvnBadRows []int
+		expCount   int
+	}{
+		"success__flush_on_size": {
+			gvnOpts:  WriterOptions{MaxRows: 5, MaxDelay: time.Hour},
+			gvnFlush: false,
+			expCount: 20,
+		},
+		"success__flush_on_time": {
+			gvnOpts:  WriterOptions{MaxRows: 1000, MaxDelay: 10 * time.Millisecond},
+			gvnFlush: false,
+			expCount: 20,
+		},
+		"success__flush_explicitly": {
+			gvnOpts:  WriterOptions{MaxRows: 1000, MaxDelay: time.Hour},
+			gvnFlush: true,
+			expCount: 20,
+		},
+		"failure__bad_rows": {
+			gvnOpts:    WriterOptions{MaxRows: 1000, MaxDelay: time.Hour, Exec: ExecOptions{Mode: TxSavepoint, Bisect: true}},
+			gvnFlush:   true,
+			gvnBadRows: []int{3, 17},
+			expCount:   18,
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			testutil.WithTxDB(t, func(tx pg.BeginnerExecutor) {
+				// Given
+				ctx := context.Background()
+
+				_, err := tx.ExecContext(ctx, createSQL)
+				require.NoError(t, err)
+
+				writer, err := NewWriter[*SampleTable](ctx, tx, tableName, []string{"id", "col_01"}, tc.gvnOpts)
+				require.NoError(t, err)
+
+				bad := make(map[int]bool, len(tc.gvnBadRows))
+				for _, idx := range tc.gvnBadRows {
+					bad[idx] = true
+				}
+
+				// When
+				var wg sync.WaitGroup
+				futures := make([]*WriteFuture, 20)
+				for idx := range futures {
+					wg.Add(1)
+					go func(idx int) {
+						defer wg.Done()
+
+						row := &SampleTable{ID: int64(idx), Col01: fmt.Sprintf("DataRow__%d", idx)}
+						if bad[idx] {
+							row.Col01 = ""
+						}
+
+						future, err := writer.Write(ctx, row)
+						require.NoError(t, err)
+						futures[idx] = future
+					}(idx)
+				}
+				wg.Wait()
+
+				if tc.gvnFlush {
+					require.NoError(t, writer.Flush(ctx))
+				}
+
+				// Then
+				for idx, future := range futures {
+					err := future.Wait(ctx)
+					if bad[idx] {
+						var rowErr RowError
+						require.ErrorAs(t, err, &rowErr)
+						continue
+					}
+					require.NoError(t, err)
+				}
+				require.NoError(t, writer.Close(ctx))
+
+				total := 0
+				err = tx.QueryRowContext(ctx, "SELECT COUNT(1) FROM \""+tableName+"\"").Scan(&total)
+				require.NoError(t, err)
+				require.Equal(t, tc.expCount, total)
+			})
+		})
+	}
+}
+
+// fakeWriterRow is what the writers below are given
+type fakeWriterRow struct {
+	ID    int64  `boil:"id"`
+	Col01 string `boil:"col_01"`
+}
+
+// fakeWriterOp pretends to write the rows of a flush once `release` is closed, if set, failing the ones holding "bad"
+type fakeWriterOp struct {
+	data    []*fakeWriterRow
+	release chan struct{}
+}
+
+func (op fakeWriterOp) ExecTx(ctx context.Context, db boil.ContextBeginner, opts ExecOptions) (ExecResult, error) {
+	if op.release != nil {
+		<-op.release
+	}
+
+	result := ExecResult{Batches: 1}
+	for idx, row := range op.data {
+		if row.Col01 == "bad" {
+			result.RowErrors = append(result.RowErrors, RowError{DataIndex: idx, Row: row, Err: errors.New("bad row")})
+			continue
+		}
+		result.Succeeded = append(result.Succeeded, DataRange{Start: idx, End: idx + 1})
+	}
+
+	return result, result.err()
+}
+
+func TestWriter_collapse(t *testing.T) {
+	tcs := map[string]struct {
+		gvnRows  []*fakeWriterRow
+		expData  []*fakeWriterRow
+		expFails []bool
+	}{
+		"success__last_write_wins": {
+			gvnRows:  []*fakeWriterRow{{ID: 1, Col01: "a"}, {ID: 2, Col01: "b"}, {ID: 1, Col01: "c"}},
+			expData:  []*fakeWriterRow{{ID: 1, Col01: "c"}, {ID: 2, Col01: "b"}},
+			expFails: []bool{false, false, false},
+		},
+		"failure__superseded_share_the_outcome": {
+			gvnRows:  []*fakeWriterRow{{ID: 1, Col01: "a"}, {ID: 2, Col01: "b"}, {ID: 1, Col01: "bad"}},
+			expData:  []*fakeWriterRow{{ID: 1, Col01: "bad"}, {ID: 2, Col01: "b"}},
+			expFails: []bool{true, false, true},
+		},
+		"failure__superseded_bad_row": {
+			gvnRows:  []*fakeWriterRow{{ID: 1, Col01: "bad"}, {ID: 1, Col01: "a"}},
+			expData:  []*fakeWriterRow{{ID: 1, Col01: "a"}},
+			expFails: []bool{false, false},
+		},
+	}
+
+	for desc, tc := range tcs {
+		t.Run(desc, func(t *testing.T) {
+			// Given
+			ctx := context.Background()
+
+			var flushed [][]*fakeWriterRow
+			writer, err := newWriter(
+				ctx,
+				nil,
+				WriterOptions{MaxRows: 100, MaxDelay: time.Hour},
+				getConflictKey[*fakeWriterRow]([]string{"id"}, nil),
+				func(data []*fakeWriterRow) (writerOp, error) {
+					flushed = append(flushed, data)
+					return fakeWriterOp{data: data}, nil
+				},
+			)
+			require.NoError(t, err)
+
+			// When
+			futures := make([]*WriteFuture, 0, len(tc.gvnRows))
+			for _, row := range tc.gvnRows {
+				future, err := writer.Write(ctx, row)
+				require.NoError(t, err)
+				futures = append(futures, future)
+			}
+			require.NoError(t, writer.Close(ctx))
+
+			// Then
+			require.Equal(t, [][]*fakeWriterRow{tc.expData}, flushed)
+			for idx, future := range futures {
+				if tc.expFails[idx] {
+					var rowErr RowError
+					require.ErrorAs(t, future.Wait(ctx), &rowErr)
+					continue
+				}
+				require.NoError(t, future.Wait(ctx))
+			}
+		})
+	}
+}
+
+func TestWriter_Close_Blocked(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	release := make(chan struct{})
+
+	writer, err := newWriter(
+		ctx,
+		nil,
+		WriterOptions{MaxRows: 1, MaxDelay: time.Hour, BufferSize: 1},
+		nil,
+		func(data []*fakeWriterRow) (writerOp, error) {
+			return fakeWriterOp{data: data, release: release}, nil
+		},
+	)
+	require.NoError(t, err)
+
+	// the first row is being flushed, the second one waits in the buffer and the third one for room in the buffer
+	first, err := writer.Write(ctx, &fakeWriterRow{ID: 1})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(writer.incoming) == 0 }, time.Second, time.Millisecond)
+
+	second, err := writer.Write(ctx, &fakeWriterRow{ID: 2})
+	require.NoError(t, err)
+
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := writer.Write(ctx, &fakeWriterRow{ID: 3})
+		blocked <- err
+	}()
+
+	// When
+	closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	closeErr := writer.Close(closeCtx)
+
+	// Then
+	require.ErrorIs(t, closeErr, context.DeadlineExceeded)
+	require.ErrorIs(t, <-blocked, ErrWriterClosed)
+
+	close(release)
+	require.NoError(t, writer.Close(ctx))
+	require.NoError(t, first.Wait(ctx))
+	require.NoError(t, second.Wait(ctx))
+}